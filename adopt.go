@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// unsafeNameChars are the characters replaced in the names derived from the contexts
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// adoptKubeconfig adds the kubeconfig KUBECONFIG points to (the standard one if not set) to the library.
+// The name is derived from its current context unless given. A KUBECONFIG list is merged into one entry with --merge,
+// each of its files is added with --each.
+func (c *Config) adoptKubeconfig(configPath string, args []string) error {
+	files := kubeconfigPaths()
+	if len(files) > 1 {
+		switch {
+		case c.Merge && c.Each:
+			return kconf.InvalidErrorf("--merge and --each are mutually exclusive")
+		case c.Merge:
+			return c.adoptMerged(files, args)
+		case c.Each:
+			if len(args) > 0 {
+				return kconf.InvalidErrorf("--each names the kubeconfigs after their current contexts")
+			}
+			return c.adoptEach(configPath, files)
+		}
+		return kconf.InvalidErrorf("%s has %d files, expected --merge or --each", kubeConfigVar, len(files))
+	}
+
+	var file string
+	if len(files) == 1 {
+		file = files[0]
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		file = filepath.Join(home, kubeDefaultConfig)
+	}
+	if !exists(file) {
+		return kconf.NotFoundErrorf("kubeconfig not found: %s", file)
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	if e := entryOfFile(entries, file); e != nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("%s already in the library as %q", file, e.Name))
+	}
+
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	} else {
+		kc, err := loadKubeconfig(file)
+		if err != nil {
+			return err
+		}
+		if name, err = contextName(kc); err != nil {
+			return err
+		}
+	}
+
+	return c.addKubeconfig(configPath, []string{file, name})
+}
+
+// adoptMerged adds the merged kubeconfigs of the KUBECONFIG list as one entry stored like in the copy mode
+func (c *Config) adoptMerged(files, args []string) error {
+	kc, err := mergeKubeconfigs(files)
+	if err != nil {
+		return kconf.InvalidErrorf("cannot merge kubeconfigs: %w", err)
+	}
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	} else if name, err = contextName(kc); err != nil {
+		return err
+	}
+	if c.DryRun {
+		fmt.Printf("%s -> %s would be added merged\n", name, strings.Join(files, string(filepath.ListSeparator)))
+		return nil
+	}
+
+	data, err := kc.marshal()
+	if err != nil {
+		return err
+	}
+	replace, err := c.addData(data, name)
+	if err != nil {
+		return err
+	}
+	if replace {
+		c.infof("%s -> %d merged kubeconfigs replaced\n", name, len(files))
+	} else {
+		c.infof("%s -> %d merged kubeconfigs added\n", name, len(files))
+	}
+	return nil
+}
+
+// adoptEach adds the files of the KUBECONFIG list not in the library yet, named after their current contexts
+func (c *Config) adoptEach(configPath string, files []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !exists(file) {
+			continue
+		}
+		if e := entryOfFile(entries, file); e != nil {
+			c.infof("%s already in the library as %s\n", file, e.Name)
+			continue
+		}
+		kc, err := loadKubeconfig(file)
+		if err != nil {
+			return kconf.InvalidErrorf("cannot parse kubeconfig %s: %w", file, err)
+		}
+		name, err := contextName(kc)
+		if err != nil {
+			name = defaultName(file)
+		}
+		if err = c.addKubeconfig(configPath, []string{file, name}); err != nil {
+			return err
+		}
+		if entries, _, err = c.Library.Entries(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryOfFile returns the entry linking to the file, nil if none
+func entryOfFile(entries []*kconf.Entry, file string) *kconf.Entry {
+	real := kconf.Target(file)
+	for _, e := range entries {
+		if kconf.Target(e.Path) == real {
+			return e
+		}
+	}
+	return nil
+}
+
+// contextName returns the entry name derived from the current context of the kubeconfig
+func contextName(kc *Kubeconfig) (string, error) {
+	name := strings.TrimLeft(unsafeNameChars.ReplaceAllString(kc.CurrentContext, "-"), ".-")
+	if name == "" {
+		return "", kconf.InvalidErrorf("cannot derive the name from the current context %q, give it", kc.CurrentContext)
+	}
+	debugf("name %q derived from the current context %q", name, kc.CurrentContext)
+	return name, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import "github.com/alebedev87/kconf/pkg/kconf"
+
+// aliasKubeconfig adds an alias to the entry or deletes the given alias
+func (c *Config) aliasKubeconfig(configPath string, args []string) error {
+	if c.Delete {
+		return c.deleteAlias(configPath, args)
+	}
+
+	if len(args) < 2 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+	alias := args[1]
+
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if err := kconf.ValidateName(alias); err != nil {
+			return err
+		}
+		if _, ok := meta.Entries[alias]; ok {
+			return kconf.InvalidErrorf("kubeconfig already exists: %q", alias)
+		}
+		if owner := meta.AliasOwner(alias); owner != "" {
+			return kconf.InvalidErrorf("alias already exists: %q -> %s", alias, owner)
+		}
+
+		e.Meta.Aliases = append(e.Meta.Aliases, alias)
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+
+		c.infof("%s -> %s alias added\n", alias, e.Name)
+		return nil
+	})
+}
+
+// deleteAlias deletes the given alias from the entry which has it
+func (c *Config) deleteAlias(configPath string, args []string) error {
+	if len(args) < 1 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+	alias := args[0]
+
+	_, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	owner := meta.AliasOwner(alias)
+	if owner == "" {
+		return kconf.NotFoundErrorf("alias not found: %q", alias)
+	}
+
+	e := meta.Entries[owner]
+	for i, a := range e.Aliases {
+		if a == alias {
+			e.Aliases = append(e.Aliases[:i], e.Aliases[i+1:]...)
+			break
+		}
+	}
+	if err = c.Library.Save(meta); err != nil {
+		return err
+	}
+
+	c.infof("%s -> %s alias deleted\n", alias, owner)
+	return nil
+}
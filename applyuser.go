@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// applyUser replaces the users of the entries matching the --to pattern with the user of the --user-from file.
+// The previous contents are kept in the history of the entries.
+func (c *Config) applyUser(configPath string, args []string) error {
+	if c.UserFrom == "" || c.Pattern == "" {
+		return kconf.InvalidErrorf("--user-from and --to are required")
+	}
+	user, err := loadUser(c.UserFrom, c.User)
+	if err != nil {
+		return err
+	}
+
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	matched, err := matchEntries(entries, c.Pattern)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, e := range matched {
+		if e.Meta.Overlay != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: overlay kubeconfig, its user comes from %s\n", e.Name, e.Meta.Overlay.UserFile)
+			continue
+		}
+		if c.DryRun {
+			fmt.Printf("%s would use user %s\n", e.Name, user.Name)
+			continue
+		}
+
+		kc, err := loadKubeconfig(e.Path)
+		if err == nil {
+			kc.setUser(user)
+			var data []byte
+			if data, err = kc.marshal(); err == nil {
+				err = c.Library.Rewrite(e, data)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot apply user to %s: %v\n", e.Name, err)
+			failed = append(failed, e.Name)
+			continue
+		}
+		c.infof("%s uses user %s\n", e.Name, user.Name)
+	}
+
+	if !c.DryRun {
+		if err = c.Library.Save(meta); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("user not applied to %d kubeconfigs", len(failed))
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// the members of the library archives
+const (
+	archiveMetadata    = "kconf.json"
+	archiveKubeconfigs = "kubeconfigs/"
+	archiveSnapshots   = "snapshots/"
+	archiveFileMode    = 0600
+)
+
+// exportArchive writes the kubeconfigs of the library with all their metadata (aliases, tags, usage, history, profiles...)
+// to the gzipped tar archive, - is stdout
+func (c *Config) exportArchive(file string) error {
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if file != "-" {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, archiveFileMode)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = writeArchiveFile(tw, archiveMetadata, data); err != nil {
+		return err
+	}
+	snapshots := map[string]bool{}
+	for _, e := range entries {
+		if data, err = os.ReadFile(e.Path); err != nil {
+			return kconf.EntryError(e.Name, err)
+		}
+		if err = writeArchiveFile(tw, archiveKubeconfigs+e.Name, data); err != nil {
+			return err
+		}
+		for _, s := range e.Meta.History {
+			if snapshots[s.Checksum] {
+				continue
+			}
+			snapshots[s.Checksum] = true
+			file, err := c.Library.SnapshotPath(s)
+			if err == nil {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				debugf("skipping snapshot %d of %q: %v", s.Version, e.Name, err)
+				continue
+			}
+			if err = writeArchiveFile(tw, archiveSnapshots+s.Checksum, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if file != "-" {
+		c.infof("%d kubeconfigs exported to %s\n", len(entries), file)
+	}
+	return nil
+}
+
+// writeArchiveFile adds the file to the archive
+func writeArchiveFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: archiveFileMode, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// importArchive adds the kubeconfigs of the archive written by export --archive with their metadata.
+// The existing kubeconfigs are kept unless forced, the imported ones keep their indexes if they are free.
+func (c *Config) importArchive(configPath string, args []string) error {
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the archive")
+	}
+
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return kconf.InvalidErrorf("invalid archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	archived := &kconf.Metadata{}
+	kubeconfigs := map[string][]byte{}
+	snapshots := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return kconf.InvalidErrorf("invalid archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return kconf.InvalidErrorf("invalid archive: %w", err)
+		}
+		switch {
+		case hdr.Name == archiveMetadata:
+			if err = json.Unmarshal(data, archived); err != nil {
+				return kconf.InvalidErrorf("invalid archive metadata: %w", err)
+			}
+		case strings.HasPrefix(hdr.Name, archiveKubeconfigs):
+			kubeconfigs[strings.TrimPrefix(hdr.Name, archiveKubeconfigs)] = data
+		case strings.HasPrefix(hdr.Name, archiveSnapshots):
+			snapshots[strings.TrimPrefix(hdr.Name, archiveSnapshots)] = data
+		}
+	}
+
+	// the archive may come from anyone: nothing is imported unless all of it is valid
+	for sum, data := range snapshots {
+		if !kconf.IsChecksum(sum) {
+			return kconf.InvalidErrorf("invalid archive: invalid snapshot name %q", sum)
+		}
+		if actual := sha256.Sum256(data); hex.EncodeToString(actual[:]) != sum {
+			return kconf.InvalidErrorf("invalid archive: corrupted snapshot %s", sum)
+		}
+	}
+	for name, data := range kubeconfigs {
+		if am := archived.Entries[name]; am != nil {
+			if err = validateArchived(am, data); err != nil {
+				return kconf.EntryError(name, kconf.InvalidErrorf("invalid archive metadata: %w", err))
+			}
+			am.History = c.archivedHistory(am.History, snapshots)
+		}
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(kubeconfigs))
+	for name := range kubeconfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var imported []string
+	for _, name := range names {
+		if hasEntry(entries, name) && !c.Force {
+			fmt.Fprintf(os.Stderr, "skipping %s: already exists, use -f to replace it\n", name)
+			continue
+		}
+		if c.DryRun {
+			fmt.Printf("%s would be imported\n", name)
+			continue
+		}
+		if _, err = c.addData(kubeconfigs[name], name); err != nil {
+			return err
+		}
+		imported = append(imported, name)
+	}
+	if c.DryRun {
+		return nil
+	}
+	for _, data := range snapshots {
+		if _, err := c.Library.StoreSnapshot(data); err != nil {
+			return err
+		}
+	}
+
+	_, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	mergeMetadata(meta, archived, imported)
+	if err = c.Library.Save(meta); err != nil {
+		return err
+	}
+	c.infof("%d kubeconfigs imported\n", len(imported))
+	return nil
+}
+
+// validateArchived checks the archived metadata of the entry the way the commands setting them do:
+// the variables and the tunnel end up in the shell and ssh commands, the history checksums name the stored files
+func validateArchived(am *kconf.EntryMeta, kubeconfig []byte) error {
+	for _, s := range am.History {
+		if !kconf.IsChecksum(s.Checksum) {
+			return kconf.InvalidErrorf("invalid checksum %q of version %d", s.Checksum, s.Version)
+		}
+	}
+	for name := range am.Env {
+		if err := validateEnvName(name); err != nil {
+			return err
+		}
+	}
+	for _, alias := range am.Aliases {
+		if err := kconf.ValidateName(alias); err != nil {
+			return err
+		}
+	}
+	if am.Tunnel != nil && am.Proxy != "" {
+		return kconf.InvalidErrorf("both a tunnel and a proxy")
+	}
+	if am.Tunnel != nil {
+		if err := validateTunnel(am.Tunnel); err != nil {
+			return err
+		}
+	}
+	if am.Proxy != "" {
+		if err := validateProxy(am.Proxy); err != nil {
+			return err
+		}
+	}
+	if am.Patch != "" {
+		kc, err := parseKubeconfig(kubeconfig)
+		if err != nil {
+			return fmt.Errorf("cannot parse kubeconfig: %w", err)
+		}
+		if _, err = applyPatch(kc, am.Patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivedHistory returns the snapshots whose content is in the archive or already stored in the library,
+// the contents missing at export are dropped with their snapshots
+func (c *Config) archivedHistory(history []*kconf.Snapshot, snapshots map[string][]byte) []*kconf.Snapshot {
+	var res []*kconf.Snapshot
+	for _, s := range history {
+		if _, ok := snapshots[s.Checksum]; !ok && !c.Library.HasSnapshot(s.Checksum) {
+			debugf("dropping snapshot %d: content not found", s.Version)
+			continue
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// mergeMetadata copies the archived metadata of the imported entries to the library metadata,
+// along with their profiles, recent switches and the default entry if the library has none
+func mergeMetadata(meta, archived *kconf.Metadata, imported []string) {
+	isImported := map[string]bool{}
+	taken := map[int]bool{}
+	for _, name := range imported {
+		isImported[name] = true
+	}
+	for name, m := range meta.Entries {
+		if !isImported[name] {
+			taken[m.Index] = true
+		}
+	}
+
+	var reindexed []*kconf.EntryMeta
+	for _, name := range imported {
+		am, m := archived.Entries[name], meta.Entries[name]
+		if am == nil || m == nil {
+			continue
+		}
+		if am.Index > 0 && !taken[am.Index] {
+			m.Index = am.Index
+			taken[m.Index] = true
+		} else {
+			reindexed = append(reindexed, m)
+		}
+		m.AddedAt, m.LastUsed, m.Switches = am.AddedAt, am.LastUsed, am.Switches
+		m.Tags, m.Protected, m.Env = am.Tags, am.Protected, am.Env
+		// the pieces of the overlays are files of the exporting machine
+		m.Tunnel, m.Proxy, m.Patch = am.Tunnel, am.Proxy, am.Patch
+		m.History = am.History
+		m.Aliases = nil
+		for _, alias := range am.Aliases {
+			if owner := meta.AliasOwner(alias); owner == "" && meta.Entries[alias] == nil {
+				m.Aliases = append(m.Aliases, alias)
+			}
+		}
+	}
+	// the entries whose indexes are taken get the lowest free ones
+	idx := 1
+	for _, m := range reindexed {
+		for taken[idx] {
+			idx++
+		}
+		m.Index = idx
+		taken[idx] = true
+	}
+
+	for name, p := range archived.Profiles {
+		if !isImported[p.Entry] {
+			continue
+		}
+		if meta.Profiles == nil {
+			meta.Profiles = map[string]*kconf.Profile{}
+		}
+		if _, ok := meta.Profiles[name]; !ok {
+			meta.Profiles[name] = p
+		}
+	}
+	for _, s := range archived.Recent {
+		if isImported[s.Entry] {
+			meta.Recent = append(meta.Recent, s)
+		}
+	}
+	sort.SliceStable(meta.Recent, func(i, j int) bool {
+		return meta.Recent[i].Time.After(meta.Recent[j].Time)
+	})
+	if len(meta.Recent) > maxRecentSwitches {
+		meta.Recent = meta.Recent[:maxRecentSwitches]
+	}
+	if meta.Default == "" && isImported[archived.Default] {
+		meta.Default = archived.Default
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	defaultProbeTimeout = 3 * time.Second
+	defaultProbeWorkers = 8
+	// retryBackoff is the delay before the first retry, doubled for each next one
+	retryBackoff = 200 * time.Millisecond
+)
+
+// netOptions configure the requests sent to the API servers
+type netOptions struct {
+	// Timeout is the timeout of a single request
+	Timeout time.Duration
+	// Retries is the number of times the failed requests are retried
+	Retries int
+	// Workers is the number of the API servers queried concurrently
+	Workers int
+}
+
+// Cluster statuses reported by the probes
+const (
+	statusReachable    = "reachable"
+	statusUnreachable  = "unreachable"
+	statusUnauthorized = "unauthorized"
+	// statusUnchecked is the status of the reachable servers rejecting the credentials kconf cannot send: exec plugins and auth-providers
+	statusUnchecked = "unchecked"
+	statusUnknown   = "unknown"
+)
+
+// isDown returns true if the status is the one of a server which didn't answer:
+// the servers rejecting the credentials are up
+func isDown(status string) bool {
+	return status == statusUnreachable || status == statusUnknown
+}
+
+// validate checks the options are usable
+func (o netOptions) validate() error {
+	switch {
+	case o.Timeout <= 0:
+		return kconf.InvalidErrorf("timeout must be positive: %s", o.Timeout)
+	case o.Retries < 0:
+		return kconf.InvalidErrorf("retries cannot be negative: %d", o.Retries)
+	case o.Workers < 1:
+		return kconf.InvalidErrorf("workers must be positive: %d", o.Workers)
+	}
+	return nil
+}
+
+// apiClient is an HTTP client of the API server of the kubeconfig current context
+type apiClient struct {
+	server   string
+	client   *http.Client
+	retries  int
+	token    string
+	username string
+	password string
+}
+
+// newAPIClient returns the client configured with the server and the static credentials of the current context.
+// The relative file paths are resolved against the directory of the kubeconfig file.
+func newAPIClient(kc *Kubeconfig, kubeconfigFile string, opts netOptions) (*apiClient, error) {
+	ctx := kc.context(kc.CurrentContext)
+	if ctx == nil {
+		return nil, fmt.Errorf("current context not found: %q", kc.CurrentContext)
+	}
+	cluster := kc.cluster(ctx.Cluster)
+	if cluster == nil || cluster.Server == "" {
+		return nil, fmt.Errorf("no server for context %q", kc.CurrentContext)
+	}
+	user := kc.user(ctx.User)
+	if user == nil {
+		user = &User{}
+	}
+
+	// the link target is the file the paths are relative to
+	if real, err := filepath.EvalSymlinks(kubeconfigFile); err == nil {
+		kubeconfigFile = real
+	}
+	dir := filepath.Dir(kubeconfigFile)
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cluster.InsecureSkipTLSVerify,
+		ServerName:         cluster.TLSServerName,
+	}
+
+	ca, err := dataOrFile(cluster.CertificateAuthorityData, cluster.CertificateAuthority, dir)
+	if err != nil {
+		return nil, err
+	}
+	if ca != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid certificate authority")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	cert, err := dataOrFile(user.ClientCertificateData, user.ClientCertificate, dir)
+	if err != nil {
+		return nil, err
+	}
+	key, err := dataOrFile(user.ClientKeyData, user.ClientKey, dir)
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil && key != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+
+	token := user.Token
+	if token == "" && user.TokenFile != "" {
+		data, err := os.ReadFile(resolvePath(user.TokenFile, dir))
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+	if cluster.ProxyURL != "" {
+		proxy, err := url.Parse(cluster.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &apiClient{
+		server:   cluster.Server,
+		client:   &http.Client{Transport: transport, Timeout: opts.Timeout},
+		retries:  opts.Retries,
+		token:    token,
+		username: user.Username,
+		password: user.Password,
+	}, nil
+}
+
+// get sends the authenticated GET request to the given API path
+func (a *apiClient) get(ctx context.Context, apiPath string) (*http.Response, error) {
+	return a.do(ctx, http.MethodGet, apiPath, nil)
+}
+
+// do sends the authenticated request with the JSON body (none if nil) to the given API path.
+// The requests failed to be sent and the ones answered with a server error are retried.
+func (a *apiClient) do(ctx context.Context, method, apiPath string, body []byte) (*http.Response, error) {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, a.server+apiPath, reader)
+		if err != nil {
+			return nil, err
+		}
+		if a.token != "" {
+			req.Header.Set("Authorization", "Bearer "+a.token)
+		} else if a.username != "" {
+			req.SetBasicAuth(a.username, a.password)
+		}
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		debugf("%s %s%s", method, a.server, apiPath)
+		resp, err := a.client.Do(req)
+		if attempt == a.retries || (err == nil && resp.StatusCode < http.StatusInternalServerError) {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+
+		debugf("retrying in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// call sends the request and decodes the JSON response into out, failing on a status other than the expected one
+func (a *apiClient) call(method, apiPath string, body []byte, expected int, out interface{}) error {
+	resp, err := a.do(context.Background(), method, apiPath, body)
+	if err != nil {
+		return networkErrorf("%v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// dataOrFile returns the base64 decoded data or the content of the file if the data is empty (nil if both are empty)
+func dataOrFile(data, file, dir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(resolvePath(file, dir))
+	}
+	return nil, nil
+}
+
+// resolvePath returns the file path relative to the given directory unless it's absolute
+func resolvePath(file, dir string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(dir, file)
+}
+
+// probeResult is the outcome of the API server probe
+type probeResult struct {
+	Status     string        `json:"status"`
+	HTTPStatus int           `json:"http_status,omitempty"`
+	Latency    time.Duration `json:"latency_ns,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// probe checks the reachability of the API server of the kubeconfig and the validity of its credentials
+func probe(kubeconfigFile string, opts netOptions) *probeResult {
+	kc, err := loadKubeconfig(kubeconfigFile)
+	if err != nil {
+		return &probeResult{Status: statusUnknown, Error: err.Error()}
+	}
+	client, err := newAPIClient(kc, kubeconfigFile, opts)
+	if err != nil {
+		return &probeResult{Status: statusUnknown, Error: err.Error()}
+	}
+
+	start := time.Now()
+	resp, err := client.get(context.Background(), "/api")
+	latency := time.Since(start)
+	if err != nil {
+		return &probeResult{Status: statusUnreachable, Latency: latency, Error: err.Error()}
+	}
+	resp.Body.Close()
+
+	res := &probeResult{Status: statusReachable, HTTPStatus: resp.StatusCode, Latency: latency}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		res.Status = statusUnauthorized
+		// the request was anonymous
+		if u := kc.currentUser(); u != nil && (u.Exec != nil || u.AuthProvider != nil) {
+			res.Status = statusUnchecked
+		}
+	}
+	return res
+}
+
+// forEachConcurrently calls the function for all the entries using the given number of workers
+func forEachConcurrently(entries []*kconf.Entry, workers int, fn func(*kconf.Entry)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan *kconf.Entry)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				fn(e)
+			}
+		}()
+	}
+
+	for _, e := range entries {
+		jobs <- e
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// serverVersion returns the Kubernetes version of the API server of the kubeconfig
+func serverVersion(kubeconfigFile string, opts netOptions) (string, error) {
+	kc, err := loadKubeconfig(kubeconfigFile)
+	if err != nil {
+		return "", err
+	}
+	client, err := newAPIClient(kc, kubeconfigFile, opts)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.get(context.Background(), "/version")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var info struct {
+		GitVersion string `json:"gitVersion"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	return info.GitVersion, nil
+}
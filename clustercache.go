@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	clusterCacheFile       = "clusters.json"
+	defaultClusterCacheTTL = time.Hour
+)
+
+// ClusterData is the data fetched from the API server of an entry
+type ClusterData struct {
+	// Server is the API server the data was fetched from
+	Server    string       `json:"server"`
+	FetchedAt time.Time    `json:"fetched_at"`
+	Status    *probeResult `json:"status"`
+	Version   string       `json:"version,omitempty"`
+}
+
+// clusterCache is the data fetched from the API servers, keyed by the entry name
+type clusterCache struct {
+	Entries map[string]*ClusterData `json:"entries"`
+}
+
+// loadClusterCache reads the cluster cache of the library (empty if it doesn't exist or is corrupted)
+func loadClusterCache(configPath string) *clusterCache {
+	cache := &clusterCache{}
+	file := path.Join(configPath, cacheDir, clusterCacheFile)
+	if data, err := os.ReadFile(file); err == nil {
+		if err = json.Unmarshal(data, cache); err != nil {
+			debugf("ignoring corrupted cluster cache %s: %v", file, err)
+		}
+	}
+	if cache.Entries == nil {
+		cache.Entries = map[string]*ClusterData{}
+	}
+	return cache
+}
+
+// save writes the cluster cache to the cache directory of the library
+func (cc *clusterCache) save(configPath string) error {
+	dir := path.Join(configPath, cacheDir)
+	if err := os.MkdirAll(dir, confDirFileMode); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cc)
+	if err != nil {
+		return err
+	}
+	return kconf.WriteFileAtomic(path.Join(dir, clusterCacheFile), data, cacheFileMode)
+}
+
+// cacheTTL returns the time the data fetched from the clusters stays valid
+func (c *Config) cacheTTL() time.Duration {
+	if c.Settings.CacheTTL == "" {
+		return defaultClusterCacheTTL
+	}
+	ttl, err := time.ParseDuration(c.Settings.CacheTTL)
+	if err != nil {
+		debugf("invalid cache TTL %q, using %s", c.Settings.CacheTTL, defaultClusterCacheTTL)
+		return defaultClusterCacheTTL
+	}
+	return ttl
+}
+
+// clusterData returns the data of the entry API servers keyed by the entry name.
+// The data is fetched concurrently, only for the entries not cached within the TTL (all of them if refresh is requested).
+func (c *Config) clusterData(configPath string, entries []*kconf.Entry, infos map[string]*EntryInfo) map[string]*ClusterData {
+	cache := loadClusterCache(configPath)
+	ttl := c.cacheTTL()
+
+	var stale []*kconf.Entry
+	for _, e := range entries {
+		data, ok := cache.Entries[e.Name]
+		if !c.Refresh && ok && data.Server == infos[e.Name].Server && time.Since(data.FetchedAt) < ttl {
+			continue
+		}
+		stale = append(stale, e)
+	}
+
+	var mu sync.Mutex
+	forEachConcurrently(stale, c.Net.Workers, func(e *kconf.Entry) {
+		data := fetchClusterData(e.Path, c.Net)
+		data.Server = infos[e.Name].Server
+		mu.Lock()
+		cache.Entries[e.Name] = data
+		mu.Unlock()
+	})
+
+	res := map[string]*ClusterData{}
+	for _, e := range entries {
+		res[e.Name] = cache.Entries[e.Name]
+	}
+
+	if len(stale) > 0 {
+		// drop the removed entries
+		cache.Entries = res
+		if err := cache.save(configPath); err != nil {
+			debugf("cannot write cluster cache: %v", err)
+		}
+	}
+	return res
+}
+
+// fetchClusterData probes the API server of the kubeconfig and queries its version
+func fetchClusterData(kubeconfigFile string, opts netOptions) *ClusterData {
+	data := &ClusterData{
+		FetchedAt: time.Now(),
+		Status:    probe(kubeconfigFile, opts),
+	}
+
+	if isDown(data.Status.Status) {
+		return data
+	}
+
+	// the version is often available without valid credentials
+	version, err := serverVersion(kubeconfigFile, opts)
+	if err != nil {
+		debugf("cannot get version of %s: %v", kubeconfigFile, err)
+	}
+	data.Version = version
+	return data
+}
+
+func (c *Config) manageCache(configPath string, args []string) error {
+	if len(args) != 1 || args[0] != "clear" {
+		return kconf.InvalidErrorf("unknown cache operation, expected: clear")
+	}
+
+	dir := path.Join(configPath, cacheDir)
+	if c.DryRun {
+		fmt.Printf("%s would be removed\n", dir)
+		return nil
+	}
+
+	debugf("removing %s", dir)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	c.infof("cache cleared\n")
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// ansiColors are the ANSI escape codes of the color names
+var ansiColors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"bold":    "1",
+}
+
+// defaultTagColors are the colors of the entries by their key=value tags
+var defaultTagColors = map[string]string{
+	"env=prod":    "red",
+	"env=staging": "yellow",
+	"env=dev":     "green",
+}
+
+// Color modes
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+const noColorVar = "NO_COLOR"
+
+// colorEnabled returns true if the output is colored: always or never if requested,
+// otherwise if the output goes to a terminal and NO_COLOR is not set
+func (c *Config) colorEnabled() bool {
+	switch c.Color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	if os.Getenv(noColorVar) != "" {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// tagColor returns the color name of the entry with the given tags (empty if none).
+// The first matching key=value in the alphabetical order wins.
+func (c *Config) tagColor(tags map[string]string) string {
+	colors := c.Settings.Theme.TagColors
+	if colors == nil {
+		colors = defaultTagColors
+	}
+
+	keys := make([]string, 0, len(colors))
+	for kv := range colors {
+		keys = append(keys, kv)
+	}
+	sort.Strings(keys)
+
+	for _, kv := range keys {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && tags[parts[0]] == parts[1] {
+			return colors[kv]
+		}
+	}
+	return ""
+}
+
+// colorize wraps the text into the escape sequences of the colors, the trailing newline stays outside.
+// Unknown color names are ignored.
+func colorize(text string, colors ...string) string {
+	var codes []string
+	for _, color := range colors {
+		if code, ok := ansiColors[color]; ok {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return text
+	}
+
+	trimmed := strings.TrimSuffix(text, "\n")
+	return "\033[" + strings.Join(codes, ";") + "m" + trimmed + "\033[0m" + text[len(trimmed):]
+}
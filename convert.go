@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	// kubeloginAzureCommand is the exec plugin replacing the azure auth-provider
+	kubeloginAzureCommand   = "kubelogin"
+	defaultAzureEnvironment = "AzurePublicCloud"
+	awsIAMAuthenticator     = "aws-iam-authenticator"
+	// alphaExecAPIVersion is the ExecCredential version removed in Kubernetes 1.24
+	alphaExecAPIVersion = "client.authentication.k8s.io/v1alpha1"
+)
+
+// conversion rewrites the deprecated authentication of the user, returns false if it doesn't apply
+type conversion struct {
+	name    string
+	convert func(*User) bool
+}
+
+// conversions returns the conversions selected by the flags
+func (c *Config) conversions() []conversion {
+	var res []conversion
+	if c.AzureKubelogin {
+		res = append(res, conversion{name: "kubelogin", convert: azureToKubelogin})
+	}
+	if c.AWSCLI {
+		res = append(res, conversion{name: "aws eks get-token", convert: iamAuthenticatorToAWSCLI})
+	}
+	return res
+}
+
+// convertKubeconfigs rewrites the deprecated authentication stanzas of the users of the given entries, all of them with --all.
+// The previous contents are kept in the history of the entries.
+func (c *Config) convertKubeconfigs(configPath string, args []string) error {
+	conversions := c.conversions()
+	if len(conversions) == 0 {
+		return kconf.InvalidErrorf("expected a conversion: --azure-kubelogin, --aws-cli")
+	}
+
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	var targets []*kconf.Entry
+	switch {
+	case c.All && len(args) > 0:
+		return kconf.InvalidErrorf("--all cannot be used with kubeconfig names")
+	case c.All:
+		targets = entries
+	case len(args) > 0:
+		if targets, err = c.selectEntries(entries, args); err != nil {
+			return err
+		}
+	default:
+		return kconf.InvalidErrorf("expected the kubeconfig names or --all")
+	}
+
+	var converted int
+	for _, e := range targets {
+		kc, err := loadKubeconfig(e.Path)
+		if err != nil {
+			return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+		}
+
+		var users []string
+		for i := range kc.Users {
+			u := &kc.Users[i]
+			for _, conv := range conversions {
+				if conv.convert(&u.User) {
+					users = append(users, u.Name)
+					if c.DryRun {
+						fmt.Printf("user %s of %s would be converted to %s\n", u.Name, e.Name, conv.name)
+					} else {
+						c.infof("user %s of %s converted to %s\n", u.Name, e.Name, conv.name)
+					}
+				}
+			}
+		}
+		if len(users) == 0 {
+			// the selection of all the entries is expected to catch the unconverted ones
+			if !c.All {
+				c.infof("nothing to convert in %s\n", e.Name)
+			}
+			continue
+		}
+		converted++
+		if c.DryRun {
+			continue
+		}
+
+		data, err := kc.marshal()
+		if err != nil {
+			return err
+		}
+		if err = c.Library.Rewrite(e, data); err != nil {
+			return kconf.EntryError(e.Name, err)
+		}
+		debugf("users %s of %s converted", strings.Join(users, ", "), e.Name)
+	}
+	if converted == 0 && c.All {
+		c.infof("nothing to convert\n")
+	}
+	if converted == 0 || c.DryRun {
+		return nil
+	}
+	return c.Library.Save(meta)
+}
+
+// azureToKubelogin replaces the azure auth-provider rejected by the recent kubectl versions with the kubelogin exec plugin.
+// The cached tokens of the provider are dropped, kubelogin gets its own.
+func azureToKubelogin(u *User) bool {
+	if u.AuthProvider == nil || u.AuthProvider.Name != "azure" {
+		return false
+	}
+	cfg := u.AuthProvider.Config
+	env := cfg["environment"]
+	if env == "" {
+		env = defaultAzureEnvironment
+	}
+	command := []string{kubeloginAzureCommand, "get-token",
+		"--environment", env,
+		"--server-id", cfg["apiserver-id"],
+		"--client-id", cfg["client-id"],
+		"--tenant-id", cfg["tenant-id"],
+	}
+	// the default config mode requests the audience with the spn: prefix
+	if mode := cfg["config-mode"]; mode == "" || mode == "0" {
+		command = append(command, "--legacy")
+	}
+	plugin := execPlugin(command)
+	plugin.APIVersion = betaExecAPIVersion
+	u.AuthProvider = nil
+	u.Exec = plugin
+	return true
+}
+
+// iamAuthenticatorToAWSCLI replaces the aws-iam-authenticator exec plugin with aws eks get-token,
+// the exec plugins of the aws command still using the removed v1alpha1 API get the v1beta1 one
+func iamAuthenticatorToAWSCLI(u *User) bool {
+	if u.Exec == nil {
+		return false
+	}
+	switch strings.TrimSuffix(filepath.Base(u.Exec.Command), ".exe") {
+	case "aws":
+		if u.Exec.APIVersion != alphaExecAPIVersion {
+			return false
+		}
+		u.Exec.APIVersion = betaExecAPIVersion
+		return true
+	case awsIAMAuthenticator:
+	default:
+		return false
+	}
+
+	var cluster, role, region string
+	args := u.Exec.Args
+	for i := 0; i < len(args); i++ {
+		name, value := args[i], ""
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			name, value = parts[0], parts[1]
+		} else if strings.HasPrefix(name, "-") && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "-i", "--cluster-id":
+			cluster = value
+		case "-r", "--role":
+			role = value
+		case "--region":
+			region = value
+		}
+	}
+	if cluster == "" {
+		debugf("no cluster ID in the arguments of %s: %v", awsIAMAuthenticator, args)
+		return false
+	}
+
+	command := []string{"aws", "eks", "get-token", "--cluster-name", cluster}
+	if role != "" {
+		command = append(command, "--role-arn", role)
+	}
+	if region != "" {
+		command = append(command, "--region", region)
+	}
+	command = append(command, "--output", "json")
+	plugin := execPlugin(command)
+	plugin.APIVersion = betaExecAPIVersion
+	// the profile and the credentials of the environment apply to the aws command as well
+	plugin.Env = u.Exec.Env
+	u.Exec = plugin
+	return true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	// execInfoVar holds the ExecCredential object client-go passes to the exec plugins
+	execInfoVar = "KUBERNETES_EXEC_INFO"
+	// defaultExecAPIVersion is the ExecCredential version used if client-go passes none
+	defaultExecAPIVersion = "client.authentication.k8s.io/v1"
+	// betaExecAPIVersion is the ExecCredential version of the cloud provider plugins
+	betaExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+)
+
+// CredentialSource is where the token of a credential is retrieved from, the first set field wins
+type CredentialSource struct {
+	// Command is the shell command printing the token
+	Command string `json:"command"`
+	// Env is the environment variable holding the token
+	Env string `json:"env"`
+	// File is the file holding the token
+	File string `json:"file"`
+}
+
+// execCredential is the ExecCredential object of the client authentication API
+type execCredential struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Status     *execCredentialStatus `json:"status,omitempty"`
+}
+
+type execCredentialStatus struct {
+	Token string `json:"token"`
+}
+
+// printCredential prints the ExecCredential with the token of the named credential: kconf is the exec plugin of the kubeconfig
+func (c *Config) printCredential(configPath string, args []string) error {
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the credential name")
+	}
+	name := args[0]
+	source, ok := c.Settings.Credentials[name]
+	if !ok {
+		return kconf.NotFoundErrorf("credential not found: %q", name)
+	}
+
+	token, err := source.token()
+	if err != nil {
+		return fmt.Errorf("cannot get token of %q: %w", name, err)
+	}
+	if token == "" {
+		return fmt.Errorf("empty token of %q", name)
+	}
+
+	cred := execCredential{APIVersion: defaultExecAPIVersion}
+	if info := os.Getenv(execInfoVar); info != "" {
+		if err = json.Unmarshal([]byte(info), &cred); err != nil {
+			return kconf.InvalidErrorf("invalid %s: %w", execInfoVar, err)
+		}
+		if cred.APIVersion == "" {
+			cred.APIVersion = defaultExecAPIVersion
+		}
+	}
+	cred.Kind = "ExecCredential"
+	cred.Status = &execCredentialStatus{Token: token}
+	return json.NewEncoder(os.Stdout).Encode(cred)
+}
+
+// token retrieves the token from the source
+func (s CredentialSource) token() (string, error) {
+	switch {
+	case s.Command != "":
+		debugf("running %q", s.Command)
+		cmd := exec.Command("sh", "-c", s.Command)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		// the prompts of the command go to the terminal, stdout is read by client-go
+		cmd.Stderr = os.Stderr
+		cmd.Stdin = os.Stdin
+		if err := cmd.Run(); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(out.String()), nil
+	case s.Env != "":
+		return strings.TrimSpace(os.Getenv(s.Env)), nil
+	case s.File != "":
+		data, err := os.ReadFile(s.File)
+		return strings.TrimSpace(string(data)), err
+	}
+	return "", kconf.InvalidErrorf("no command, env or file")
+}
+
+// execPlugin returns the exec plugin configuration running the command line
+func execPlugin(command []string) *ExecConfig {
+	return &ExecConfig{
+		APIVersion:      defaultExecAPIVersion,
+		Command:         command[0],
+		Args:            command[1:],
+		InteractiveMode: "IfAvailable",
+	}
+}
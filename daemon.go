@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	// daemonUnit is the systemd user unit running the watch
+	daemonUnit = "kconf-watch.service"
+	// daemonLabel is the label of the launchd agent running the watch
+	daemonLabel = "io.github.alebedev87.kconf.watch"
+)
+
+// daemon installs or uninstalls the user service running kconf watch on the directory: daemon install <dir>, daemon uninstall.
+// The service is a systemd user unit, a launchd agent on macOS. It only adds the dropped kubeconfigs,
+// the cluster data is still fetched on demand by ls and serve.
+func (c *Config) daemon(configPath string, args []string) error {
+	if len(args) == 0 {
+		return kconf.InvalidErrorf("unknown daemon operation, expected: install, uninstall")
+	}
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return kconf.InvalidErrorf("daemon is supported on Linux and macOS only")
+	}
+	switch args[0] {
+	case "install":
+		if len(args) != 2 {
+			return kconf.InvalidErrorf("expected the directory to watch")
+		}
+		return c.installDaemon(args[1])
+	case "uninstall":
+		if len(args) != 1 {
+			return kconf.InvalidErrorf("unexpected arguments: %v", args[1:])
+		}
+		return c.uninstallDaemon()
+	}
+	return kconf.InvalidErrorf("unknown daemon operation %q, expected: install, uninstall", args[0])
+}
+
+// installDaemon writes the service watching the directory and starts it, it's started at login then
+func (c *Config) installDaemon(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return kconf.InvalidErrorf("not a directory: %s", dir)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	command := []string{self, "watch"}
+	if c.Force {
+		command = append(command, "-f")
+	}
+	command = append(command, dir)
+	// set by --library as well, the default library is found the same way by the service
+	env := map[string]string{}
+	if lib := os.Getenv(confPathVar); lib != "" {
+		// the service doesn't run in the current directory
+		if lib, err = filepath.Abs(lib); err != nil {
+			return err
+		}
+		env[confPathVar] = lib
+	}
+
+	file, err := daemonFile()
+	if err != nil {
+		return err
+	}
+	var content string
+	var start [][]string
+	if runtime.GOOS == "darwin" {
+		content = launchdPlist(command, env)
+		start = [][]string{{"launchctl", "unload", file}, {"launchctl", "load", "-w", file}}
+	} else {
+		content = systemdUnit(dir, command, env)
+		start = [][]string{{"systemctl", "--user", "daemon-reload"}, {"systemctl", "--user", "enable", "--now", daemonUnit}, {"systemctl", "--user", "restart", daemonUnit}}
+	}
+	if c.DryRun {
+		fmt.Printf("%s would be written:\n%s", file, content)
+		return nil
+	}
+
+	if err = os.MkdirAll(path.Dir(file), confDirFileMode); err != nil {
+		return err
+	}
+	if err = kconf.WriteFileAtomic(file, []byte(content), 0644); err != nil {
+		return err
+	}
+	debugf("wrote %s", file)
+	for i, args := range start {
+		// the agent is not loaded on the first install
+		if err = runService(args); err != nil && !(runtime.GOOS == "darwin" && i == 0) {
+			return err
+		}
+	}
+	c.infof("watching %s with %s\n", dir, file)
+	return nil
+}
+
+// uninstallDaemon stops the service and removes its file
+func (c *Config) uninstallDaemon() error {
+	file, err := daemonFile()
+	if err != nil {
+		return err
+	}
+	if !exists(file) {
+		return kconf.NotFoundErrorf("daemon not installed: %s", file)
+	}
+	if c.DryRun {
+		fmt.Printf("%s would be removed\n", file)
+		return nil
+	}
+
+	if runtime.GOOS == "darwin" {
+		err = runService([]string{"launchctl", "unload", "-w", file})
+	} else {
+		err = runService([]string{"systemctl", "--user", "disable", "--now", daemonUnit})
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(file); err != nil {
+		return err
+	}
+	if runtime.GOOS != "darwin" {
+		if err = runService([]string{"systemctl", "--user", "daemon-reload"}); err != nil {
+			return err
+		}
+	}
+	c.infof("%s removed\n", file)
+	return nil
+}
+
+// daemonFile returns the file of the systemd user unit, of the launchd agent on macOS
+func daemonFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "darwin" {
+		return path.Join(home, "Library/LaunchAgents", daemonLabel+".plist"), nil
+	}
+	dir := path.Join(home, ".config")
+	if d := os.Getenv(xdgConfigVar); filepath.IsAbs(d) {
+		dir = d
+	}
+	return path.Join(dir, "systemd/user", daemonUnit), nil
+}
+
+// runService runs the service manager command, its output goes to stderr
+func runService(args []string) error {
+	debugf("run %s", strings.Join(args, " "))
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// systemdUnit returns the systemd user unit running the command with the environment, restarted if it fails
+func systemdUnit(dir string, command []string, env map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=kconf watching %s\n\n[Service]\n", strings.ReplaceAll(dir, "%", "%%"))
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		quoted = append(quoted, systemdQuote(arg))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(quoted, " "))
+	for k, v := range env {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(k+"="+v))
+	}
+	b.WriteString("Restart=on-failure\n\n[Install]\nWantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdQuote returns the double quoted argument of the unit with the escaped specifiers
+func systemdQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(s) + `"`
+}
+
+// launchdPlist returns the launchd agent running the command with the environment at login, restarted if it fails
+func launchdPlist(command []string, env map[string]string) string {
+	escape := func(s string) string {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "  <key>Label</key>\n  <string>%s</string>\n  <key>ProgramArguments</key>\n  <array>\n", daemonLabel)
+	for _, arg := range command {
+		fmt.Fprintf(&b, "    <string>%s</string>\n", escape(arg))
+	}
+	b.WriteString("  </array>\n")
+	if len(env) > 0 {
+		b.WriteString("  <key>EnvironmentVariables</key>\n  <dict>\n")
+		for k, v := range env {
+			fmt.Fprintf(&b, "    <key>%s</key>\n    <string>%s</string>\n", escape(k), escape(v))
+		}
+		b.WriteString("  </dict>\n")
+	}
+	b.WriteString(`  <key>RunAtLoad</key>
+  <true/>
+  <key>KeepAlive</key>
+  <dict>
+    <key>SuccessfulExit</key>
+    <false/>
+  </dict>
+</dict>
+</plist>
+`)
+	return b.String()
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"io"
+	"log"
+)
+
+// debugLog is the debug logger, enabled by the debug flag
+var debugLog = log.New(io.Discard, "debug: ", log.Ltime|log.Lmicroseconds)
+
+// debugf logs the debug message
+func debugf(format string, a ...interface{}) {
+	debugLog.Printf(format, a...)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// defaultKubeconfig marks the entry set by set without arguments, prints the default entry if none is given
+func (c *Config) defaultKubeconfig(configPath string, args []string) error {
+	if c.Delete {
+		_, meta, err := c.Library.Entries()
+		if err != nil {
+			return err
+		}
+		if meta.Default == "" {
+			return kconf.NotFoundErrorf("no default kubeconfig")
+		}
+		name := meta.Default
+		meta.Default = ""
+		if err = c.Library.Save(meta); err != nil {
+			return err
+		}
+		c.infof("%s is not default anymore\n", name)
+		return nil
+	}
+
+	if len(args) == 0 {
+		_, meta, err := c.Library.Entries()
+		if err != nil {
+			return err
+		}
+		if meta.Default == "" {
+			return kconf.NotFoundErrorf("no default kubeconfig")
+		}
+		fmt.Println(meta.Default)
+		return nil
+	}
+
+	return c.makeKubeconfig(configPath, args, func(e *kconf.Entry, meta *kconf.Metadata) error {
+		meta.Default = e.Name
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+		c.infof("%s is default\n", e.Name)
+		return nil
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	awsConfigVar     = "AWS_CONFIG_FILE"
+	awsProfileVar    = "AWS_PROFILE"
+	awsDefaultConfig = ".aws/config"
+	defaultEKSPrefix = "eks/"
+)
+
+// awsProfile is a profile of the AWS config signing in with AWS SSO (IAM Identity Center)
+type awsProfile struct {
+	Name    string
+	Account string
+	Role    string
+	Region  string
+}
+
+// eksCluster is the part of aws eks describe-cluster used for the kubeconfig
+type eksCluster struct {
+	Cluster struct {
+		Name                 string `json:"name"`
+		Arn                  string `json:"arn"`
+		Endpoint             string `json:"endpoint"`
+		CertificateAuthority struct {
+			Data string `json:"data"`
+		} `json:"certificateAuthority"`
+	} `json:"cluster"`
+}
+
+// importEKS adds a kubeconfig for each EKS cluster reachable with the AWS SSO profiles, named prefix/profile/cluster.
+// The credentials come from aws eks get-token with the profile of the account.
+func (c *Config) importEKS(configPath string, args []string) error {
+	profiles, err := ssoProfiles()
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, p := range profiles {
+		if c.Pattern != "" {
+			if ok, err := path.Match(c.Pattern, p.Name); err != nil {
+				return kconf.InvalidErrorf("invalid pattern %q: %w", c.Pattern, err)
+			} else if !ok {
+				continue
+			}
+		}
+		region := c.Region
+		if region == "" {
+			region = p.Region
+		}
+		if region == "" {
+			fmt.Fprintf(os.Stderr, "skipping AWS profile %s: no region, use --region\n", p.Name)
+			continue
+		}
+
+		var list struct {
+			Clusters []string `json:"clusters"`
+		}
+		if err = awsJSON(p, &list, "eks", "list-clusters", "--region", region); err != nil {
+			return err
+		}
+		sort.Strings(list.Clusters)
+		for _, name := range list.Clusters {
+			var cluster eksCluster
+			if err = awsJSON(p, &cluster, "eks", "describe-cluster", "--name", name, "--region", region); err != nil {
+				return err
+			}
+			entry := c.Prefix + p.Name + "/" + name
+			kc := eksKubeconfig(&cluster, p, region)
+			if c.DryRun {
+				c.infof("would add %s (account %s, role %s)\n", entry, p.Account, p.Role)
+				continue
+			}
+			data, err := kc.marshal()
+			if err != nil {
+				return err
+			}
+			if err = c.addEKS(data, entry); err != nil {
+				return err
+			}
+			c.infof("%s added (account %s, role %s)\n", entry, p.Account, p.Role)
+			added++
+		}
+	}
+	if !c.DryRun {
+		c.infof("%d EKS kubeconfigs added\n", added)
+	}
+	return nil
+}
+
+// addEKS adds the kubeconfig under the library lock: the clusters are listed without holding it
+func (c *Config) addEKS(data []byte, name string) error {
+	unlock, err := c.Library.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	_, err = c.addData(data, name)
+	return err
+}
+
+// eksKubeconfig returns the kubeconfig of the cluster using the token of aws eks get-token, named like aws eks update-kubeconfig does
+func eksKubeconfig(cluster *eksCluster, p *awsProfile, region string) *Kubeconfig {
+	arn := cluster.Cluster.Arn
+	plugin := execPlugin([]string{"aws", "eks", "get-token", "--cluster-name", cluster.Cluster.Name, "--region", region, "--output", "json"})
+	plugin.APIVersion = betaExecAPIVersion
+	plugin.Env = []ExecEnvVar{{Name: awsProfileVar, Value: p.Name}}
+	return &Kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		CurrentContext: arn,
+		Clusters: []NamedCluster{{Name: arn, Cluster: Cluster{
+			Server:                   cluster.Cluster.Endpoint,
+			CertificateAuthorityData: cluster.Cluster.CertificateAuthority.Data,
+		}}},
+		Contexts: []NamedContext{{Name: arn, Context: Context{Cluster: arn, User: arn}}},
+		Users:    []NamedUser{{Name: arn, User: User{Exec: plugin}}},
+	}
+}
+
+// awsJSON runs the aws command with the profile and decodes its JSON output
+func awsJSON(p *awsProfile, out interface{}, args ...string) error {
+	args = append(args, "--profile", p.Name, "--output", "json")
+	debugf("aws %s", strings.Join(args, " "))
+	cmd := exec.Command("aws", args...)
+	cmd.Stderr = os.Stderr
+	data, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return networkErrorf("aws %s failed for profile %s (expired session? run aws sso login --profile %s): %w", args[1], p.Name, p.Name, err)
+	}
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("cannot parse output of aws %s: %w", args[1], err)
+	}
+	return nil
+}
+
+// ssoProfiles returns the profiles of the AWS config which have an SSO account
+func ssoProfiles() ([]*awsProfile, error) {
+	file := os.Getenv(awsConfigVar)
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(home, awsDefaultConfig)
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, kconf.NotFoundErrorf("AWS config not found: %s", file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []*awsProfile
+	var curr *awsProfile
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			curr = nil
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			if section == "default" || strings.HasPrefix(section, "profile ") {
+				curr = &awsProfile{Name: strings.TrimSpace(strings.TrimPrefix(section, "profile "))}
+				res = append(res, curr)
+			}
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || curr == nil {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+		switch strings.TrimSpace(kv[0]) {
+		case "sso_account_id":
+			curr.Account = value
+		case "sso_role_name":
+			curr.Role = value
+		case "region":
+			curr.Region = value
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sso := res[:0]
+	for _, p := range res {
+		if p.Account != "" {
+			sso = append(sso, p)
+		}
+	}
+	if len(sso) == 0 {
+		return nil, kconf.NotFoundErrorf("no AWS SSO profile in %s, create them with aws configure sso", file)
+	}
+	return sso, nil
+}
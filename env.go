@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const defaultShell = "/bin/sh"
+
+// cloudBindings are the tags binding the entries to cloud credentials, with the variables the cloud CLIs read them from.
+// The exec plugins of the kubeconfigs (aws eks get-token, gke-gcloud-auth-plugin) run with the right account.
+var cloudBindings = map[string]string{
+	"aws_profile":    "AWS_PROFILE",
+	"gcloud_project": "CLOUDSDK_CORE_PROJECT",
+}
+
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// entryEnv returns the NAME=value environment variables set along with KUBECONFIG for the entry, sorted by name.
+// The variables of the entry take precedence over the cloud bindings of its tags.
+func entryEnv(meta *kconf.EntryMeta) []string {
+	vars := map[string]string{}
+	for tag, name := range cloudBindings {
+		if v, ok := meta.Tags[tag]; ok && v != "" {
+			vars[name] = v
+		}
+	}
+	for name, v := range meta.Env {
+		vars[name] = v
+	}
+
+	env := make([]string, 0, len(vars))
+	for name, v := range vars {
+		env = append(env, name+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// staleEnv returns the names of the variables of the active entry of the state which the entry doesn't set
+func staleEnv(st *activeState, meta *kconf.Metadata, e *kconf.Entry) []string {
+	prev, ok := meta.Entries[st.Entry]
+	if !ok {
+		return nil
+	}
+	set := map[string]bool{}
+	for _, kv := range entryEnv(e.Meta) {
+		set[strings.SplitN(kv, "=", 2)[0]] = true
+	}
+	var res []string
+	for _, kv := range entryEnv(prev) {
+		if name := strings.SplitN(kv, "=", 2)[0]; !set[name] {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// validateEnvName checks the name of a variable exported by set: it's evaluated by the shell
+func validateEnvName(name string) error {
+	if !envVarName.MatchString(name) || name == kubeConfigVar {
+		return kconf.InvalidErrorf("invalid variable name %q", name)
+	}
+	return nil
+}
+
+// envKubeconfig sets the NAME=value environment variables of the entry or deletes the given ones, prints them without variables
+func (c *Config) envKubeconfig(configPath string, args []string) error {
+	if len(args) == 0 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+
+	vars := map[string]string{}
+	for _, arg := range args[1:] {
+		kv := strings.SplitN(arg, "=", 2)
+		if err := validateEnvName(kv[0]); err != nil {
+			return err
+		}
+		if c.Delete {
+			vars[kv[0]] = ""
+			continue
+		}
+		if len(kv) != 2 {
+			return kconf.InvalidErrorf("invalid variable %q: expected NAME=value", arg)
+		}
+		vars[kv[0]] = kv[1]
+	}
+
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if len(vars) == 0 {
+			for _, kv := range entryEnv(e.Meta) {
+				fmt.Println(kv)
+			}
+			return nil
+		}
+
+		if e.Meta.Env == nil {
+			e.Meta.Env = map[string]string{}
+		}
+		for k, v := range vars {
+			if c.Delete {
+				delete(e.Meta.Env, k)
+			} else {
+				e.Meta.Env[k] = v
+			}
+		}
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+
+		c.infof("%s env: %s\n", e.Name, formatTags(e.Meta.Env))
+		return nil
+	})
+}
+
+// startShell runs the user shell with the entry
+func (c *Config) startShell(configPath string, args []string) error {
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the kubeconfig name")
+	}
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	e, err := c.findEntry(entries, args[0])
+	if err != nil {
+		return err
+	}
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = defaultShell
+	}
+	return c.runWith(configPath, e, func(string) ([]string, error) {
+		return []string{shell}, nil
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// Exit codes of the program.
+// The flag parsing errors exit with 2 (flag package).
+const (
+	exitOK         = 0
+	exitFailure    = 1
+	exitNotFound   = 3
+	exitInvalid    = 4
+	exitPermission = 5
+	exitNetwork    = 6
+)
+
+// reasons are the machine readable names of the exit codes
+var reasons = map[int]string{
+	exitFailure:    "failure",
+	exitNotFound:   "not_found",
+	exitInvalid:    "invalid",
+	exitPermission: "permission_denied",
+	exitNetwork:    "network",
+}
+
+// codeError is an error which results in a specific exit code
+type codeError struct {
+	code int
+	err  error
+	// silent errors are not printed, they answer the predicates
+	silent bool
+}
+
+func (e *codeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codeError) Unwrap() error {
+	return e.err
+}
+
+// networkErrorf returns an error about unreachable clusters
+func networkErrorf(format string, a ...interface{}) error {
+	return &codeError{code: exitNetwork, err: fmt.Errorf(format, a...)}
+}
+
+// exitCode returns the program exit code for the given error
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var ce *codeError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	if errors.Is(err, kconf.ErrNotFound) {
+		return exitNotFound
+	}
+	if errors.Is(err, kconf.ErrInvalid) {
+		return exitInvalid
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return exitPermission
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return exitNotFound
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return exitNetwork
+	}
+	return exitFailure
+}
+
+// jsonError is the machine readable form of an error
+type jsonError struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	Entry   string `json:"entry,omitempty"`
+}
+
+// printJSONError writes the error as a JSON object
+func printJSONError(w io.Writer, err error) {
+	je := jsonError{
+		Code:    exitCode(err),
+		Message: err.Error(),
+	}
+	je.Reason = reasons[je.Code]
+
+	var ke *kconf.Error
+	if errors.As(err, &ke) {
+		je.Entry = ke.Entry
+	}
+
+	// nothing left to report a failure to
+	_ = json.NewEncoder(w).Encode(struct {
+		Error jsonError `json:"error"`
+	}{je})
+}
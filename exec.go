@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// execKubeconfig runs the command with KUBECONFIG set to the entry, the tunnel opened for the command lives as long as it
+func (c *Config) execKubeconfig(configPath string, args []string) error {
+	// the flags parsing stops at the name, the separator is left
+	if len(args) > 1 && args[1] == "--" {
+		args = append(args[:1], args[2:]...)
+	}
+	if len(args) < 2 {
+		return kconf.InvalidErrorf("expected the kubeconfig name and the command")
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	e, err := c.findEntry(entries, args[0])
+	if err != nil {
+		return err
+	}
+	return c.runWith(configPath, e, func(string) ([]string, error) {
+		return args[1:], nil
+	})
+}
+
+// runWith runs the command with KUBECONFIG set to the entry once confirmed if it's guarded.
+// The command line is built from the path of the kubeconfig, the exit code of the command is the one of kconf.
+func (c *Config) runWith(configPath string, e *kconf.Entry, build func(file string) ([]string, error)) error {
+	if err := c.guard(e); err != nil {
+		return err
+	}
+
+	file, opened, err := c.kubeconfigFile(configPath, e)
+	if err != nil {
+		return err
+	}
+	// the tunnel already opened by set stays for the shell
+	if opened {
+		defer c.closeTunnel(configPath, e.Name, e.Meta.Tunnel)
+	}
+
+	command, err := build(file)
+	if err != nil {
+		return err
+	}
+	debugf("running %v with %s=%s", command, kubeConfigVar, file)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(append(os.Environ(), kubeConfigVar+"="+file), entryEnv(e.Meta)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &codeError{code: exitErr.ExitCode(), err: err}
+	}
+	return err
+}
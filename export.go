@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// exportKubeconfig prints the kubeconfig of the entry.
+// The sanitized kubeconfig has no credentials, only the cluster coordinates:
+// the users are emptied or use the --exec placeholder plugin, the certificate authority files are embedded.
+func (c *Config) exportKubeconfig(configPath string, args []string) error {
+	if c.Archive != "" {
+		if len(args) != 0 {
+			return kconf.InvalidErrorf("the archive has all the kubeconfigs, no name expected")
+		}
+		return c.exportArchive(c.Archive)
+	}
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the kubeconfig name")
+	}
+
+	return c.makeKubeconfig(configPath, args, func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if !c.Sanitized && c.Exec == "" {
+			data, err := os.ReadFile(e.Path)
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+
+		kc, err := loadKubeconfig(e.Path)
+		if err != nil {
+			return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+		}
+		dir := filepath.Dir(kconf.Target(e.Path))
+		for i := range kc.Clusters {
+			cluster := &kc.Clusters[i].Cluster
+			if cluster.CertificateAuthority == "" {
+				continue
+			}
+			// the file doesn't exist on the other side
+			ca, err := os.ReadFile(resolvePath(cluster.CertificateAuthority, dir))
+			if err != nil {
+				return err
+			}
+			cluster.CertificateAuthority = ""
+			cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(ca)
+		}
+
+		var exec *ExecConfig
+		if command := strings.Fields(c.Exec); len(command) > 0 {
+			exec = execPlugin(command)
+		}
+		for i := range kc.Users {
+			// the unknown fields may hold credentials too
+			kc.Users[i].User = User{Exec: exec}
+		}
+
+		data, err := kc.marshal()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// printExportScript prints the shell script of aliases equivalent to the library which doesn't need kconf:
+// the entries export KUBECONFIG to their files, the profiles also switch the context and the namespace with kubectl.
+func (c *Config) printExportScript(configPath string, args []string) error {
+	var selector map[string]string
+	if c.Tag != "" {
+		k, v, err := parseTag(c.Tag)
+		if err != nil {
+			return err
+		}
+		selector = map[string]string{k: v}
+	}
+
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("#!/bin/sh\n# kubeconfigs of the library %s, to be sourced\n", configPath)
+	byName := map[string]*kconf.Entry{}
+	for _, e := range entries {
+		byName[e.Name] = e
+		if selector != nil && !matchTags(e.Meta.Tags, selector) {
+			continue
+		}
+		if e.Meta.Tunnel != nil || e.Meta.Proxy != "" || e.Meta.Patch != "" {
+			fmt.Printf("# %s: the tunnel, the proxy or the patch is not applied\n", e.Name)
+		}
+		cmd := strings.Join(exportCommands(e), "; ")
+		for _, name := range append([]string{e.Name}, e.Meta.Aliases...) {
+			fmt.Printf("alias %s=%s\n", c.AliasPrefix+unsafeAliasChars.ReplaceAllString(name, "_"), shellQuote(cmd))
+		}
+	}
+
+	names := make([]string, 0, len(meta.Profiles))
+	for name := range meta.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := meta.Profiles[name]
+		e, ok := byName[p.Entry]
+		if !ok || (selector != nil && !matchTags(e.Meta.Tags, selector)) {
+			continue
+		}
+		// kubectl switches the context and the namespace in the kubeconfig itself
+		cmds := exportCommands(e)
+		if p.Context != "" {
+			cmds = append(cmds, "kubectl config use-context "+shellQuote(p.Context)+" >/dev/null")
+		}
+		if p.Namespace != "" {
+			cmds = append(cmds, "kubectl config set-context --current --namespace="+shellQuote(p.Namespace)+" >/dev/null")
+		}
+		fmt.Printf("alias %s=%s\n", c.AliasPrefix+unsafeAliasChars.ReplaceAllString(name, "_"), shellQuote(strings.Join(cmds, "; ")))
+	}
+	return nil
+}
+
+// exportCommands returns the commands exporting KUBECONFIG to the file of the entry and its other variables
+func exportCommands(e *kconf.Entry) []string {
+	cmds := []string{"export " + kubeConfigVar + "=" + shellQuote(kconf.Target(e.Path))}
+	for _, kv := range entryEnv(e.Meta) {
+		kv := strings.SplitN(kv, "=", 2)
+		cmds = append(cmds, "export "+kv[0]+"="+shellQuote(kv[1]))
+	}
+	return cmds
+}
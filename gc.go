@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultGCAge is the age of the temporary files removed by gc: younger ones may belong to a running command
+const defaultGCAge = time.Hour
+
+// collectGarbage removes the stored kubeconfig copies not used anymore, the stale temporary files and working copies
+func (c *Config) collectGarbage(configPath string, args []string) error {
+	removed, err := c.Library.GC(c.OlderThan, c.DryRun)
+	if err == nil {
+		// the working copies of the removed entries may hold resolved secrets
+		var copies []string
+		copies, err = c.collectWorkCopies(configPath, c.OlderThan, c.DryRun)
+		removed = append(removed, copies...)
+	}
+	for _, file := range removed {
+		if c.DryRun {
+			fmt.Printf("%s would be removed\n", file)
+		} else {
+			c.infof("%s removed\n", file)
+		}
+	}
+	return err
+}
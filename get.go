@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+	"gopkg.in/yaml.v3"
+)
+
+// getField prints a field of the kubeconfig of the entry: get <name> <field>, or the --jsonpath template.
+// The fields are the ones of the current context, the context of the entry/context name if given.
+func (c *Config) getField(configPath string, args []string) error {
+	if c.JSONPath == "" && len(args) != 2 || c.JSONPath != "" && len(args) != 1 {
+		return kconf.InvalidErrorf("expected the kubeconfig name and the field or --jsonpath")
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	e, context, err := c.findContext(entries, args[0])
+	if err != nil {
+		return err
+	}
+	kc, err := loadKubeconfig(e.Path)
+	if err != nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	if context != "" {
+		kc.CurrentContext = context
+	}
+	data, err := kc.marshal()
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	var out string
+	if c.JSONPath != "" {
+		out, err = evalJSONPath(doc, c.JSONPath)
+	} else {
+		out, err = contextField(kc, doc, args[1])
+	}
+	if err != nil {
+		return kconf.EntryError(e.Name, err)
+	}
+	fmt.Println(out)
+	return nil
+}
+
+// contextField returns the field of the current context: context, cluster, user, namespace, server
+// or a dotted path in the context, cluster or user like user.exec.command
+func contextField(kc *Kubeconfig, doc interface{}, field string) (string, error) {
+	ctx := kc.context(kc.CurrentContext)
+	if ctx == nil {
+		return "", kconf.NotFoundErrorf("current context not found: %q", kc.CurrentContext)
+	}
+	switch field {
+	case "context":
+		return kc.CurrentContext, nil
+	case "namespace":
+		if ctx.Namespace == "" {
+			return "default", nil
+		}
+		return ctx.Namespace, nil
+	case "server":
+		cluster := kc.cluster(ctx.Cluster)
+		if cluster == nil || cluster.Server == "" {
+			return "", kconf.NotFoundErrorf("no server for context %q", kc.CurrentContext)
+		}
+		return cluster.Server, nil
+	}
+
+	parts := strings.Split(field, ".")
+	var list, name string
+	switch parts[0] {
+	case "context":
+		list, name = "contexts", kc.CurrentContext
+	case "cluster":
+		list, name = "clusters", ctx.Cluster
+	case "user":
+		list, name = "users", ctx.User
+	default:
+		return "", kconf.InvalidErrorf("unknown field %q, expected context, cluster, user, namespace, server or a path in the context, cluster or user", field)
+	}
+	if len(parts) == 1 {
+		return name, nil
+	}
+
+	path := fmt.Sprintf(`{.%s[?(@.name==%q)].%s.%s}`, list, name, parts[0], strings.Join(parts[1:], "."))
+	out, err := evalJSONPath(doc, path)
+	if err == nil && out == "" {
+		err = kconf.NotFoundErrorf("field not found: %q", field)
+	}
+	return out, err
+}
+
+// evalJSONPath renders the kubectl JSONPath template: the text outside the braces is kept,
+// the expressions support the children (.name, ['name']), the indexes ([0], [*]) and the filters ([?(@.key=="value")]).
+// The values of an expression are separated by spaces, the objects are printed as JSON.
+func evalJSONPath(doc interface{}, tmpl string) (string, error) {
+	var b strings.Builder
+	for tmpl != "" {
+		start := strings.Index(tmpl, "{")
+		if start < 0 {
+			b.WriteString(tmpl)
+			break
+		}
+		end := strings.Index(tmpl[start:], "}")
+		if end < 0 {
+			return "", kconf.InvalidErrorf("unclosed expression in %q", tmpl)
+		}
+		b.WriteString(tmpl[:start])
+		expr := strings.TrimSpace(tmpl[start+1 : start+end])
+		tmpl = tmpl[start+end+1:]
+
+		if strings.HasPrefix(expr, `"`) {
+			s, err := strconv.Unquote(expr)
+			if err != nil {
+				return "", kconf.InvalidErrorf("invalid string %s: %w", expr, err)
+			}
+			b.WriteString(s)
+			continue
+		}
+		values, err := selectPath([]interface{}{doc}, strings.TrimPrefix(expr, "$"))
+		if err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			s, err := formatValue(v)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		}
+	}
+	return b.String(), nil
+}
+
+// selectPath returns the values reached by the expression from the given ones
+func selectPath(values []interface{}, expr string) ([]interface{}, error) {
+	for expr != "" {
+		var next []interface{}
+		switch {
+		case strings.HasPrefix(expr, "[?("):
+			end := strings.Index(expr, ")]")
+			if end < 0 {
+				return nil, kconf.InvalidErrorf("unclosed filter in %q", expr)
+			}
+			key, want, err := parseFilter(expr[3:end])
+			if err != nil {
+				return nil, err
+			}
+			expr = expr[end+2:]
+			for _, v := range values {
+				for _, item := range children(v) {
+					got, err := selectPath([]interface{}{item}, key)
+					if err == nil && len(got) == 1 && fmt.Sprint(got[0]) == want {
+						next = append(next, item)
+					}
+				}
+			}
+		case strings.HasPrefix(expr, "["):
+			end := strings.Index(expr, "]")
+			if end < 0 {
+				return nil, kconf.InvalidErrorf("unclosed index in %q", expr)
+			}
+			index := expr[1:end]
+			expr = expr[end+1:]
+			for _, v := range values {
+				switch {
+				case index == "*":
+					next = append(next, children(v)...)
+				case strings.HasPrefix(index, "'") || strings.HasPrefix(index, `"`):
+					if m, ok := v.(map[string]interface{}); ok {
+						if child, ok := m[strings.Trim(index, `'"`)]; ok {
+							next = append(next, child)
+						}
+					}
+				default:
+					i, err := strconv.Atoi(index)
+					if err != nil {
+						return nil, kconf.InvalidErrorf("invalid index %q", index)
+					}
+					if l, ok := v.([]interface{}); ok {
+						if i < 0 {
+							i += len(l)
+						}
+						if i >= 0 && i < len(l) {
+							next = append(next, l[i])
+						}
+					}
+				}
+			}
+		case strings.HasPrefix(expr, "."):
+			expr = expr[1:]
+			end := strings.IndexAny(expr, ".[")
+			if end < 0 {
+				end = len(expr)
+			}
+			key := expr[:end]
+			expr = expr[end:]
+			if key == "" {
+				// the root, or the children of the filters and indexes
+				next = values
+				break
+			}
+			for _, v := range values {
+				if m, ok := v.(map[string]interface{}); ok {
+					if child, ok := m[key]; ok {
+						next = append(next, child)
+					}
+				}
+			}
+		default:
+			return nil, kconf.InvalidErrorf("invalid expression %q", expr)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// parseFilter returns the path and the value of the filter of the form @.key=="value"
+func parseFilter(filter string) (string, string, error) {
+	parts := strings.SplitN(filter, "==", 2)
+	if len(parts) != 2 || !strings.HasPrefix(strings.TrimSpace(parts[0]), "@") {
+		return "", "", kconf.InvalidErrorf("unsupported filter %q, expected @.key==\"value\"", filter)
+	}
+	key := strings.TrimPrefix(strings.TrimSpace(parts[0]), "@")
+	want := strings.TrimSpace(parts[1])
+	if unquoted, err := strconv.Unquote(want); err == nil {
+		want = unquoted
+	} else {
+		want = strings.Trim(want, "'")
+	}
+	return key, want, nil
+}
+
+// children returns the items of the list or the values of the map
+func children(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		res := make([]interface{}, 0, len(v))
+		for _, k := range keys {
+			res = append(res, v[k])
+		}
+		return res
+	}
+	return nil
+}
+
+func formatValue(v interface{}) (string, error) {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	case nil:
+		return "", nil
+	}
+	return fmt.Sprint(v), nil
+}
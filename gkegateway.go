@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	gkeGatewayScheme   = "gke-gateway"
+	gkeGatewayEndpoint = "https://connectgateway.googleapis.com/v1"
+	// gkeAuthPlugin prints the tokens of the gcloud account
+	gkeAuthPlugin     = "gke-gcloud-auth-plugin"
+	gkeGlobalLocation = "global"
+)
+
+// isGKEGateway returns true if the source of the added kubeconfig is a fleet membership
+func isGKEGateway(source string) bool {
+	return strings.HasPrefix(source, gkeGatewayScheme+"://")
+}
+
+// gkeGatewayKubeconfig returns the kubeconfig reaching the fleet membership
+// gke-gateway://project/[location/]membership through the Connect Gateway, with the membership name
+func gkeGatewayKubeconfig(source string) (*Kubeconfig, string, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, "", kconf.InvalidErrorf("invalid fleet membership %q: %w", source, err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host == "" || parts[0] == "" || len(parts) > 2 {
+		return nil, "", kconf.InvalidErrorf("invalid fleet membership %q: expected %s://project/[location/]membership", source, gkeGatewayScheme)
+	}
+	project, location, membership := u.Host, gkeGlobalLocation, parts[len(parts)-1]
+	if len(parts) == 2 {
+		location = parts[0]
+	}
+
+	name := fmt.Sprintf("connectgateway_%s_%s_%s", project, location, membership)
+	plugin := execPlugin([]string{gkeAuthPlugin})
+	plugin.APIVersion = betaExecAPIVersion
+	plugin.ProvideClusterInfo = true
+	return &Kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		CurrentContext: name,
+		Clusters: []NamedCluster{{Name: name, Cluster: Cluster{
+			Server: fmt.Sprintf("%s/projects/%s/locations/%s/gkeMemberships/%s", gkeGatewayEndpoint, project, location, membership),
+		}}},
+		Contexts: []NamedContext{{Name: name, Context: Context{Cluster: name, User: name}}},
+		Users:    []NamedUser{{Name: name, User: User{Exec: plugin}}},
+	}, membership, nil
+}
+
+// addGKEGateway adds the Connect Gateway kubeconfig of the fleet membership, named after the membership if no name is given
+func (c *Config) addGKEGateway(source, name string) error {
+	kc, membership, err := gkeGatewayKubeconfig(source)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = membership
+	}
+	if c.DryRun {
+		fmt.Printf("%s -> %s would be added\n", name, kc.Clusters[0].Cluster.Server)
+		return nil
+	}
+
+	data, err := kc.marshal()
+	if err != nil {
+		return err
+	}
+	replace, err := c.addData(data, name)
+	if err != nil {
+		return err
+	}
+	if replace {
+		c.infof("%s -> %s replaced\n", name, kc.Clusters[0].Cluster.Server)
+	} else {
+		c.infof("%s -> %s added\n", name, kc.Clusters[0].Cluster.Server)
+	}
+	return nil
+}
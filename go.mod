@@ -1,3 +1,8 @@
 module github.com/alebedev87/kconf
 
 go 1.16
+
+require (
+	github.com/fsnotify/fsnotify v1.5.4
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// grepKubeconfigs prints the lines of the entry kubeconfigs matching the regular expression
+func (c *Config) grepKubeconfigs(configPath string, args []string) error {
+	if len(args) < 1 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+
+	expr := args[0]
+	if c.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return kconf.InvalidErrorf("invalid pattern %q: %w", args[0], err)
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, e := range entries {
+		matched, err := grepFile(e.Path, re, func(n int, line string) {
+			fmt.Printf("%s:%d:%s\n", e.Name, n, line)
+		})
+		if err != nil {
+			debugf("cannot search %s: %v", e.Path, err)
+			continue
+		}
+		found = found || matched
+	}
+
+	if !found {
+		return kconf.NotFoundErrorf("no matches for %q", args[0])
+	}
+	return nil
+}
+
+// grepFile calls the match function with the number and the text of all the file lines matching the regular expression
+func grepFile(file string, re *regexp.Regexp, match func(int, string)) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	found := false
+	scanner := bufio.NewScanner(f)
+	// the embedded certificates make long lines
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if re.MatchString(scanner.Text()) {
+			found = true
+			match(n, scanner.Text())
+		}
+	}
+	return found, scanner.Err()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// defaultGuardTags are the tags of the entries which require a confirmation to be activated
+var defaultGuardTags = map[string]string{"env": "prod"}
+
+// guard asks for the confirmation to activate the entry if it's guarded by its tags.
+// The prompt goes to stderr as stdout is evaluated by the shell.
+func (c *Config) guard(e *kconf.Entry) error {
+	selector := c.Settings.Guard.Tags
+	if selector == nil {
+		selector = defaultGuardTags
+	}
+	if !matchTags(e.Meta.Tags, selector) {
+		return nil
+	}
+
+	if c.Settings.Guard.Banner {
+		fmt.Fprintf(os.Stderr, "\033[1;37;41m !!! %s: %s !!! \033[0m\n", e.Name, formatTags(e.Meta.Tags))
+	}
+	if c.Yes {
+		return nil
+	}
+	if !confirm(fmt.Sprintf("Activate guarded kubeconfig %q (%s)?", e.Name, formatTags(e.Meta.Tags))) {
+		return fmt.Errorf("aborted")
+	}
+	return nil
+}
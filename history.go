@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	// maxRecentSwitches is the number of the switches kept in the metadata
+	maxRecentSwitches     = 100
+	defaultRecentSwitches = 10
+)
+
+// printHistory prints the snapshots of the entry kubeconfig, or the last switches if no entry is given
+func (c *Config) printHistory(configPath string, args []string) error {
+	if len(args) == 0 {
+		return c.printSwitches()
+	}
+	if strings.HasPrefix(args[0], "!") {
+		return c.switchAgain(configPath, args[0])
+	}
+
+	return c.makeKubeconfig(configPath, args, func(e *kconf.Entry, meta *kconf.Metadata) error {
+		history := c.Library.History(e)
+
+		if c.Output == outputJSON {
+			if history == nil {
+				history = []*kconf.Snapshot{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(history)
+		}
+
+		if len(history) == 0 {
+			c.infof("no history of %s\n", e.Name)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, s := range history {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, humanizeSince(s.Time), s.ShortChecksum())
+		}
+		return w.Flush()
+	})
+}
+
+// printSwitches prints the last switches, the latest first
+func (c *Config) printSwitches() error {
+	_, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	recent := meta.Recent
+	if c.Limit >= 0 && len(recent) > c.Limit {
+		recent = recent[:c.Limit]
+	}
+
+	if c.Output == outputJSON {
+		if recent == nil {
+			recent = []*kconf.Switch{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(recent)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, s := range recent {
+		name := s.Entry
+		if s.Context != "" {
+			name += "/" + s.Context
+		}
+		if s.Profile != "" {
+			name = "profile " + s.Profile
+		}
+		fmt.Fprintf(w, "!%d\t%s\t%s\n", i+1, name, humanizeSince(s.Time))
+	}
+	return w.Flush()
+}
+
+// switchAgain activates again the entry of the !N switch, !1 being the latest
+func (c *Config) switchAgain(configPath, arg string) error {
+	n, err := strconv.Atoi(arg[1:])
+	if err != nil || n < 1 {
+		return kconf.InvalidErrorf("invalid switch %q, expected !N", arg)
+	}
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	if n > len(meta.Recent) {
+		return kconf.NotFoundErrorf("no switch %s, %d recorded", arg, len(meta.Recent))
+	}
+
+	s := meta.Recent[n-1]
+	if s.Profile != "" {
+		if _, ok := meta.Profiles[s.Profile]; !ok {
+			return kconf.NotFoundErrorf("profile not found: %q", s.Profile)
+		}
+	}
+	e, err := kconf.FindName(entries, s.Entry)
+	if err != nil {
+		return err
+	}
+	return c.activate(configPath, e, meta, s.Profile, s.Context)
+}
+
+// rollbackKubeconfig points the entry to one of its snapshots
+func (c *Config) rollbackKubeconfig(configPath string, args []string) error {
+	if len(args) < 2 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+	version, err := strconv.Atoi(args[1])
+	if err != nil || version < 1 {
+		return kconf.InvalidErrorf("invalid version %q", args[1])
+	}
+
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if c.DryRun {
+			fmt.Printf("%s would be rolled back to version %d\n", e.Name, version)
+			return nil
+		}
+		snap, err := c.Library.Rollback(e, version)
+		if err != nil {
+			return err
+		}
+		if err = c.Library.Save(meta); err != nil {
+			return err
+		}
+		c.infof("%s rolled back to version %d\n", e.Name, snap.Version)
+		return nil
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	cacheDir                   = ".cache"
+	indexCacheFile             = "index.json"
+	cacheFileMode  os.FileMode = 0644
+)
+
+// EntryInfo is the information parsed from the kubeconfig of an entry
+type EntryInfo struct {
+	Target    string    `json:"target"`
+	ModTime   time.Time `json:"mod_time"`
+	Size      int64     `json:"size"`
+	Checksum  string    `json:"checksum,omitempty"`
+	Context   string    `json:"context,omitempty"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Server    string    `json:"server,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Contexts  []string  `json:"contexts,omitempty"`
+	// Error is the reason the kubeconfig couldn't be read or parsed
+	Error string `json:"error,omitempty"`
+}
+
+// indexCache is the cached information of all the entries.
+// The link targets are trusted while the modification times of the directories containing the links don't change,
+// the parsed information is trusted while the modification time and size of the kubeconfig files don't change.
+type indexCache struct {
+	Dirs    map[string]time.Time  `json:"dirs"`
+	Entries map[string]*EntryInfo `json:"entries"`
+}
+
+// loadEntryInfos returns the information about the given entries, only the changed kubeconfigs are parsed
+func loadEntryInfos(configPath string, entries []*kconf.Entry) map[string]*EntryInfo {
+	file := path.Join(configPath, cacheDir, indexCacheFile)
+	cache := &indexCache{}
+	if data, err := os.ReadFile(file); err == nil {
+		if err = json.Unmarshal(data, cache); err != nil {
+			debugf("ignoring corrupted index cache %s: %v", file, err)
+			cache = &indexCache{}
+		}
+	}
+
+	dirs := map[string]time.Time{}
+	res := map[string]*EntryInfo{}
+	changed := len(cache.Entries) != len(entries)
+	for _, e := range entries {
+		dir := path.Dir(e.Path)
+		if _, ok := dirs[dir]; !ok {
+			if info, err := os.Stat(dir); err == nil {
+				dirs[dir] = info.ModTime()
+			}
+		}
+
+		cached := cache.Entries[e.Name]
+		if cached != nil && !cache.Dirs[dir].Equal(dirs[dir]) {
+			// the link may point elsewhere now
+			if t := kconf.Target(e.Path); t != cached.Target {
+				cached = nil
+			}
+		}
+
+		info := parseEntryInfo(e.Path, cached)
+		if info != cached {
+			changed = true
+		}
+		res[e.Name] = info
+	}
+
+	if changed {
+		cache = &indexCache{Dirs: dirs, Entries: res}
+		if err := saveIndexCache(configPath, cache); err != nil {
+			debugf("cannot write index cache %s: %v", file, err)
+		}
+	}
+	return res
+}
+
+// parseEntryInfo returns the cached information if the kubeconfig didn't change, parses the kubeconfig otherwise
+func parseEntryInfo(linkPath string, cached *EntryInfo) *EntryInfo {
+	info := &EntryInfo{Target: kconf.Target(linkPath)}
+	if cached != nil {
+		info.Target = cached.Target
+	}
+
+	stat, err := os.Stat(linkPath)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	if cached != nil && cached.Error == "" && cached.ModTime.Equal(stat.ModTime()) && cached.Size == stat.Size() {
+		return cached
+	}
+
+	debugf("parsing kubeconfig %s", info.Target)
+	info.ModTime = stat.ModTime()
+	info.Size = stat.Size()
+
+	data, err := os.ReadFile(linkPath)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	sum := sha256.Sum256(data)
+	info.Checksum = hex.EncodeToString(sum[:])
+
+	kc, err := parseKubeconfig(data)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Context = kc.CurrentContext
+	for _, ctx := range kc.Contexts {
+		info.Contexts = append(info.Contexts, ctx.Name)
+	}
+	if ctx := kc.context(kc.CurrentContext); ctx != nil {
+		info.Cluster = ctx.Cluster
+		info.Namespace = ctx.Namespace
+		info.User = ctx.User
+		if cluster := kc.cluster(ctx.Cluster); cluster != nil {
+			info.Server = cluster.Server
+		}
+	}
+	return info
+}
+
+// saveIndexCache writes the index cache to the cache directory of the library
+func saveIndexCache(configPath string, cache *indexCache) error {
+	dir := path.Join(configPath, cacheDir)
+	if err := os.MkdirAll(dir, confDirFileMode); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return kconf.WriteFileAtomic(path.Join(dir, indexCacheFile), data, cacheFileMode)
+}
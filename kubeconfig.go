@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+	"gopkg.in/yaml.v3"
+)
+
+// Kubeconfig is the kubeconfig file.
+// The fields not used by the program are kept in Extra to write the file back unchanged.
+type Kubeconfig struct {
+	APIVersion     string                 `yaml:"apiVersion,omitempty"`
+	Kind           string                 `yaml:"kind,omitempty"`
+	CurrentContext string                 `yaml:"current-context,omitempty"`
+	Clusters       []NamedCluster         `yaml:"clusters,omitempty"`
+	Contexts       []NamedContext         `yaml:"contexts,omitempty"`
+	Users          []NamedUser            `yaml:"users,omitempty"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// NamedCluster is a cluster of the kubeconfig
+type NamedCluster struct {
+	Name    string  `yaml:"name"`
+	Cluster Cluster `yaml:"cluster"`
+}
+
+// Cluster holds the API server connection details
+type Cluster struct {
+	Server                   string                 `yaml:"server,omitempty"`
+	CertificateAuthority     string                 `yaml:"certificate-authority,omitempty"`
+	CertificateAuthorityData string                 `yaml:"certificate-authority-data,omitempty"`
+	InsecureSkipTLSVerify    bool                   `yaml:"insecure-skip-tls-verify,omitempty"`
+	ProxyURL                 string                 `yaml:"proxy-url,omitempty"`
+	TLSServerName            string                 `yaml:"tls-server-name,omitempty"`
+	Extra                    map[string]interface{} `yaml:",inline"`
+}
+
+// NamedContext is a context of the kubeconfig
+type NamedContext struct {
+	Name    string  `yaml:"name"`
+	Context Context `yaml:"context"`
+}
+
+// Context binds a cluster, a user and a namespace
+type Context struct {
+	Cluster   string                 `yaml:"cluster"`
+	User      string                 `yaml:"user"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+// NamedUser is a user of the kubeconfig
+type NamedUser struct {
+	Name string `yaml:"name"`
+	User User   `yaml:"user"`
+}
+
+// User holds the credentials
+type User struct {
+	ClientCertificate     string                 `yaml:"client-certificate,omitempty"`
+	ClientCertificateData string                 `yaml:"client-certificate-data,omitempty"`
+	ClientKey             string                 `yaml:"client-key,omitempty"`
+	ClientKeyData         string                 `yaml:"client-key-data,omitempty"`
+	Token                 string                 `yaml:"token,omitempty"`
+	TokenFile             string                 `yaml:"tokenFile,omitempty"`
+	Username              string                 `yaml:"username,omitempty"`
+	Password              string                 `yaml:"password,omitempty"`
+	Exec                  *ExecConfig            `yaml:"exec,omitempty"`
+	AuthProvider          *AuthProvider          `yaml:"auth-provider,omitempty"`
+	Extra                 map[string]interface{} `yaml:",inline"`
+}
+
+// ExecConfig is the exec credential plugin configuration
+type ExecConfig struct {
+	APIVersion         string                 `yaml:"apiVersion,omitempty"`
+	Command            string                 `yaml:"command"`
+	Args               []string               `yaml:"args,omitempty"`
+	Env                []ExecEnvVar           `yaml:"env,omitempty"`
+	InteractiveMode    string                 `yaml:"interactiveMode,omitempty"`
+	ProvideClusterInfo bool                   `yaml:"provideClusterInfo,omitempty"`
+	Extra              map[string]interface{} `yaml:",inline"`
+}
+
+// ExecEnvVar is an environment variable of the exec plugin
+type ExecEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// AuthProvider is the legacy authentication provider configuration
+type AuthProvider struct {
+	Name   string            `yaml:"name"`
+	Config map[string]string `yaml:"config,omitempty"`
+}
+
+// loadKubeconfig reads and parses the kubeconfig file
+func loadKubeconfig(file string) (*Kubeconfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseKubeconfig(data)
+}
+
+// mergeKubeconfigs merges the kubeconfigs of a KUBECONFIG list the way kubectl does: the first cluster, context and user of each name
+// and the first current context win, the missing files are skipped. The relative paths are resolved from the directories of their files.
+func mergeKubeconfigs(files []string) (*Kubeconfig, error) {
+	res := &Kubeconfig{APIVersion: "v1", Kind: "Config"}
+	seen := map[string]bool{}
+	first := func(kind, name string) bool {
+		if seen[kind+"/"+name] {
+			return false
+		}
+		seen[kind+"/"+name] = true
+		return true
+	}
+	for _, file := range files {
+		kc, err := loadKubeconfig(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		absolutePaths(kc, filepath.Dir(kconf.Target(file)))
+		if res.CurrentContext == "" {
+			res.CurrentContext = kc.CurrentContext
+		}
+		for _, c := range kc.Clusters {
+			if first("cluster", c.Name) {
+				res.Clusters = append(res.Clusters, c)
+			}
+		}
+		for _, c := range kc.Contexts {
+			if first("context", c.Name) {
+				res.Contexts = append(res.Contexts, c)
+			}
+		}
+		for _, u := range kc.Users {
+			if first("user", u.Name) {
+				res.Users = append(res.Users, u)
+			}
+		}
+	}
+	return res, nil
+}
+
+// parseKubeconfig parses the kubeconfig content
+func parseKubeconfig(data []byte) (*Kubeconfig, error) {
+	kc := &Kubeconfig{}
+	if err := yaml.Unmarshal(data, kc); err != nil {
+		return nil, err
+	}
+	return kc, nil
+}
+
+// marshal returns the kubeconfig content
+func (k *Kubeconfig) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(k); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// context returns the named context (nil if not found)
+func (k *Kubeconfig) context(name string) *Context {
+	for i := range k.Contexts {
+		if k.Contexts[i].Name == name {
+			return &k.Contexts[i].Context
+		}
+	}
+	return nil
+}
+
+// cluster returns the named cluster (nil if not found)
+func (k *Kubeconfig) cluster(name string) *Cluster {
+	for i := range k.Clusters {
+		if k.Clusters[i].Name == name {
+			return &k.Clusters[i].Cluster
+		}
+	}
+	return nil
+}
+
+// user returns the named user (nil if not found)
+func (k *Kubeconfig) user(name string) *User {
+	for i := range k.Users {
+		if k.Users[i].Name == name {
+			return &k.Users[i].User
+		}
+	}
+	return nil
+}
+
+// currentCluster returns the cluster of the current context (nil if not found)
+func (k *Kubeconfig) currentCluster() *Cluster {
+	ctx := k.context(k.CurrentContext)
+	if ctx == nil {
+		return nil
+	}
+	return k.cluster(ctx.Cluster)
+}
+
+// currentUser returns the user of the current context (nil if none)
+func (k *Kubeconfig) currentUser() *User {
+	ctx := k.context(k.CurrentContext)
+	if ctx == nil {
+		return nil
+	}
+	return k.user(ctx.User)
+}
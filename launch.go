@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// launcherData is given to the command templates of the launchers
+type launcherData struct {
+	// Name is the name of the entry
+	Name string
+	// Kubeconfig is the path of the kubeconfig the tool runs with
+	Kubeconfig string
+}
+
+// runTool runs the tool given by the first argument with the entry, see launch
+func (c *Config) runTool(configPath string, args []string) error {
+	if len(args) == 0 {
+		return kconf.InvalidErrorf("expected the tool name")
+	}
+	return c.launch(configPath, args[0], args[1:])
+}
+
+// launchK9s runs k9s with the entry, see launch
+func (c *Config) launchK9s(configPath string, args []string) error {
+	return c.launch(configPath, "k9s", args)
+}
+
+// launch runs the tool with the entry given by the first argument or selected interactively, the other arguments go to the tool.
+// The command line of the tool is its launcher template from the settings, the tool name if it has none.
+func (c *Config) launch(configPath, tool string, args []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	var e *kconf.Entry
+	if len(args) == 0 || args[0] == "--" {
+		e, err = c.pickEntry(entries)
+	} else {
+		e, err = c.findEntry(entries, args[0])
+		args = args[1:]
+	}
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	return c.runWith(configPath, e, func(file string) ([]string, error) {
+		command, err := c.launcherCommand(tool, launcherData{Name: e.Name, Kubeconfig: file})
+		if err != nil {
+			return nil, err
+		}
+		return append(command, args...), nil
+	})
+}
+
+// launcherCommand returns the command line of the tool rendered for the entry.
+// The template is split into the words before the rendering for the paths with spaces to stay whole.
+func (c *Config) launcherCommand(tool string, data launcherData) ([]string, error) {
+	launcher, ok := c.Settings.Launchers[tool]
+	if !ok {
+		return []string{tool}, nil
+	}
+
+	var command []string
+	for _, word := range strings.Fields(launcher) {
+		tmpl, err := template.New(tool).Option("missingkey=error").Parse(word)
+		if err != nil {
+			return nil, kconf.InvalidErrorf("invalid launcher %q: %w", tool, err)
+		}
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, data); err != nil {
+			return nil, kconf.InvalidErrorf("invalid launcher %q: %w", tool, err)
+		}
+		command = append(command, buf.String())
+	}
+	if len(command) == 0 {
+		return nil, kconf.InvalidErrorf("empty launcher %q", tool)
+	}
+	return command, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// Lint severities: the errors stop working with the recent kubectl versions
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// lintFinding is a problem of a cluster or a user of an entry
+type lintFinding struct {
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	// Subject is the cluster or the user with the problem
+	Subject string `json:"subject,omitempty"`
+	Message string `json:"message"`
+}
+
+// removedAuthProviders are the replacements of the auth-providers removed from client-go
+var removedAuthProviders = map[string]string{
+	"azure": "kubelogin, see kconf convert --azure-kubelogin",
+	"gcp":   gkeAuthPlugin,
+	"oidc":  "kubelogin, see kconf login oidc --kubelogin",
+}
+
+// lintKubeconfigs reports the deprecated authentication and the insecure settings of the given entries
+// (the current kubeconfig by default, all the entries with --all)
+func (c *Config) lintKubeconfigs(configPath string, args []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	targets, err := c.targetEntries(entries, args)
+	if err != nil {
+		return err
+	}
+
+	findings := []lintFinding{}
+	for _, e := range targets {
+		findings = append(findings, lintKubeconfig(e)...)
+	}
+
+	if c.Output == outputJSON {
+		if err = json.NewEncoder(os.Stdout).Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range findings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Name, f.Severity, f.Rule, f.Subject, f.Message)
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	var errs int
+	broken := map[string]bool{}
+	for _, f := range findings {
+		if f.Severity == severityError {
+			errs++
+			broken[f.Name] = true
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%d errors in %d of %d kubeconfigs", errs, len(broken), len(targets))
+	}
+	return nil
+}
+
+// lintKubeconfig returns the findings of the kubeconfig of the entry
+func lintKubeconfig(e *kconf.Entry) []lintFinding {
+	kc, err := loadKubeconfig(e.Path)
+	if err != nil {
+		return []lintFinding{{Name: e.Name, Severity: severityError, Rule: "invalid", Message: err.Error()}}
+	}
+
+	var res []lintFinding
+	add := func(severity, rule, subject, format string, a ...interface{}) {
+		res = append(res, lintFinding{Name: e.Name, Severity: severity, Rule: rule, Subject: subject, Message: fmt.Sprintf(format, a...)})
+	}
+	for _, nc := range kc.Clusters {
+		cl := nc.Cluster
+		subject := "cluster " + nc.Name
+		if cl.InsecureSkipTLSVerify {
+			add(severityWarning, "insecure-skip-tls-verify", subject, "the certificate of %s is not verified", cl.Server)
+			continue
+		}
+		u, err := url.Parse(cl.Server)
+		if err == nil && u.Scheme == "https" && cl.CertificateAuthority == "" && cl.CertificateAuthorityData == "" {
+			add(severityWarning, "missing-certificate-authority", subject, "the certificate of %s is verified with the system CAs only", cl.Server)
+		}
+	}
+	for _, nu := range kc.Users {
+		u := nu.User
+		subject := "user " + nu.Name
+		if u.Exec != nil && u.Exec.APIVersion == alphaExecAPIVersion {
+			add(severityError, "removed-exec-api", subject, "%s of %s is removed since Kubernetes 1.24, use %s", alphaExecAPIVersion, u.Exec.Command, betaExecAPIVersion)
+		}
+		if u.Exec != nil && strings.TrimSuffix(filepath.Base(u.Exec.Command), ".exe") == awsIAMAuthenticator {
+			add(severityWarning, "deprecated-exec-plugin", subject, "%s is superseded by aws eks get-token, see kconf convert --aws-cli", awsIAMAuthenticator)
+		}
+		if u.AuthProvider != nil {
+			replacement, ok := removedAuthProviders[u.AuthProvider.Name]
+			if !ok {
+				replacement = "an exec plugin"
+			}
+			add(severityError, "removed-auth-provider", subject, "auth-provider %s is removed since Kubernetes 1.26, use %s", u.AuthProvider.Name, replacement)
+		}
+		if u.Password != "" && !isSecretRef(u.Password) {
+			add(severityWarning, "basic-auth", subject, "the password of %s is stored in plaintext", u.Username)
+		}
+	}
+	return res
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+func (c *Config) listKubeconfigs(configPath string, args []string) error {
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	if implicit := kubeDefaultEntry(entries); implicit != nil {
+		entries = append(entries, implicit)
+	}
+	var currName string
+	active := activeEntry(configPath, entries)
+	if active != nil {
+		currName = active.Name
+	}
+	c.hintOutside(active)
+	if c.Contexts && (c.Status || c.ServerVersion) {
+		return kconf.InvalidErrorf("--contexts cannot be used with --status or --server-version")
+	}
+	var infos map[string]*EntryInfo
+	if c.Contexts {
+		infos = loadEntryInfos(configPath, entries)
+		if active != nil && infos[active.Name] != nil && len(infos[active.Name].Contexts) > 1 {
+			currName = active.Name + "/" + activeContext(configPath, active)
+		}
+		entries = expandContexts(entries, infos)
+	}
+
+	if c.Limit < 0 || c.Offset < 0 {
+		return kconf.InvalidErrorf("negative limit or offset")
+	}
+	if c.Offset >= len(entries) {
+		entries = nil
+	} else {
+		entries = entries[c.Offset:]
+	}
+	if c.Limit > 0 && len(entries) > c.Limit {
+		entries = entries[:c.Limit]
+	}
+	defer c.startPager()()
+
+	if c.Names {
+		for _, e := range entries {
+			fmt.Println(e.Name)
+		}
+		return nil
+	}
+
+	var tmpl *template.Template
+	if c.Format != "" {
+		if tmpl, err = template.New("format").Parse(c.Format); err != nil {
+			return kconf.InvalidErrorf("invalid format: %w", err)
+		}
+	}
+
+	if infos == nil && (c.Wide || c.Status || c.ServerVersion || c.Output == outputJSON || tmpl != nil) {
+		infos = loadEntryInfos(configPath, entries)
+	}
+
+	var clusters map[string]*ClusterData
+	if c.Status || c.ServerVersion {
+		clusters = c.clusterData(configPath, entries, infos)
+	}
+
+	if c.Output == outputJSON {
+		return c.printJSONEntries(os.Stdout, entries, infos, clusters, currName, meta.Default)
+	}
+	if tmpl != nil {
+		return c.printFormattedEntries(os.Stdout, tmpl, entries, infos, clusters, currName, meta.Default)
+	}
+
+	theme := c.Settings.Theme
+	marker := theme.ActiveMarker
+	if marker == "" {
+		marker = "*"
+	}
+	if c.Tree {
+		c.printTree(entries, currName, marker)
+		return nil
+	}
+	padding := theme.Padding
+	if padding <= 0 {
+		padding = 2
+	}
+
+	// the lines are colored after the alignment: escape sequences would break it
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, padding, ' ', 0)
+	var star string
+	for _, e := range entries {
+		if e.Name == currName {
+			star = marker + " "
+		} else {
+			star = strings.Repeat(" ", utf8.RuneCountInString(marker)+1)
+		}
+		name := e.Name
+		if len(e.Meta.Aliases) > 0 {
+			name += " (" + strings.Join(e.Meta.Aliases, ", ") + ")"
+		}
+		if c.Status {
+			name += "\t" + clusters[e.Name].Status.Status
+		}
+		if c.ServerVersion {
+			version := clusters[e.Name].Version
+			if version == "" {
+				version = "-"
+			}
+			name += "\t" + version
+		}
+		if !c.Wide {
+			fmt.Fprintf(w, "%s%s) %s\n", star, indexLabel(e), name)
+			continue
+		}
+
+		server := infos[e.Name].Server
+		if infos[e.Name].Error != "" {
+			server = "<broken>"
+		}
+		extra := ""
+		if len(e.Meta.Tags) > 0 {
+			extra += "\t" + formatTags(e.Meta.Tags)
+		}
+		if e.Meta.Protected {
+			extra += "\tprotected"
+		}
+		if e.Name == meta.Default {
+			extra += "\tdefault"
+		}
+		fmt.Fprintf(w, "%s%s) %s\t%s\tadded %s\tused %s%s\n", star, indexLabel(e), name, server, humanizeSince(e.AddedAt()), humanizeSince(e.Meta.LastUsed), extra)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	colored := c.colorEnabled()
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, e := range entries {
+		line := lines[i]
+		if colored {
+			colors := []string{c.tagColor(e.Meta.Tags)}
+			if e.Name == currName {
+				colors = append(colors, theme.ActiveColor)
+			}
+			line = colorize(line, colors...)
+		}
+		fmt.Print(line)
+	}
+	return nil
+}
+
+// indexLabel returns the index of the entry, a dash for the implicit entry
+func indexLabel(e *kconf.Entry) string {
+	if e.Meta.Index == 0 {
+		return "-"
+	}
+	return strconv.Itoa(e.Meta.Index)
+}
+
+// humanizeSince returns the time passed since the given moment in a short human readable form
+func humanizeSince(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return formatAgo(int(d/time.Minute), "m")
+	case d < 24*time.Hour:
+		return formatAgo(int(d/time.Hour), "h")
+	case d < 30*24*time.Hour:
+		return formatAgo(int(d/(24*time.Hour)), "d")
+	case d < 365*24*time.Hour:
+		return formatAgo(int(d/(30*24*time.Hour)), "mo")
+	}
+	return formatAgo(int(d/(365*24*time.Hour)), "y")
+}
+
+func formatAgo(n int, unit string) string {
+	return strconv.Itoa(n) + unit + " ago"
+}
+
+// expandContexts replaces the entries having several contexts with an entry/context row per context,
+// the information of the rows is added to the infos
+func expandContexts(entries []*kconf.Entry, infos map[string]*EntryInfo) []*kconf.Entry {
+	res := make([]*kconf.Entry, 0, len(entries))
+	for _, e := range entries {
+		info := infos[e.Name]
+		if info == nil || len(info.Contexts) < 2 {
+			res = append(res, e)
+			continue
+		}
+		kc, err := loadKubeconfig(e.Path)
+		if err != nil {
+			res = append(res, e)
+			continue
+		}
+		for _, name := range info.Contexts {
+			row := &kconf.Entry{Name: e.Name + "/" + name, Path: e.Path, Meta: e.Meta}
+			rowInfo := *info
+			rowInfo.Context, rowInfo.Cluster, rowInfo.Namespace, rowInfo.User, rowInfo.Server = name, "", "", "", ""
+			if ctx := kc.context(name); ctx != nil {
+				rowInfo.Cluster, rowInfo.Namespace, rowInfo.User = ctx.Cluster, ctx.Namespace, ctx.User
+				if cluster := kc.cluster(ctx.Cluster); cluster != nil {
+					rowInfo.Server = cluster.Server
+				}
+			}
+			infos[row.Name] = &rowInfo
+			res = append(res, row)
+		}
+	}
+	return res
+}
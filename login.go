@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	defaultOIDCScope = "openid"
+	deviceCodeGrant  = "urn:ietf:params:oauth:grant-type:device_code"
+	// defaultDeviceInterval is the polling interval of the token endpoint if the provider gives none
+	defaultDeviceInterval = 5 * time.Second
+	// kubeloginCommand is the exec plugin of kubelogin installed as a kubectl plugin
+	kubeloginCommand = "kubectl"
+)
+
+// oidcDiscovery is the part of the OpenID provider metadata used by the device flow
+type oidcDiscovery struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+// deviceAuthorization is the response of the device authorization endpoint
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	// VerificationURL is the non standard name of Google
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// oidcTokens is the response of the token endpoint, the error fields are set while the authorization is pending
+type oidcTokens struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// login adds a kubeconfig with the credentials of an identity provider: login oidc <name>
+func (c *Config) login(configPath string, args []string) error {
+	if len(args) == 0 {
+		return kconf.InvalidErrorf("unknown login method, expected: oidc")
+	}
+	switch args[0] {
+	case "oidc":
+		if len(args) != 2 {
+			return kconf.InvalidErrorf("expected the kubeconfig name")
+		}
+		return c.loginOIDC(args[1])
+	}
+	return kconf.InvalidErrorf("unknown login method %q, expected: oidc", args[0])
+}
+
+// loginOIDC adds the kubeconfig authenticated with the ID token obtained through the device authorization flow,
+// or with the kubelogin exec plugin running the flow itself and refreshing the token
+func (c *Config) loginOIDC(name string) error {
+	if c.Issuer == "" || c.ClientID == "" || c.Server == "" {
+		return kconf.InvalidErrorf("--issuer, --client-id and --server are required")
+	}
+	if c.DryRun {
+		fmt.Printf("%s -> %s would be added with OIDC credentials of %s\n", name, c.Server, c.Issuer)
+		return nil
+	}
+
+	cluster := Cluster{Server: c.Server}
+	if c.CertificateAuthority != "" {
+		ca, err := os.ReadFile(c.CertificateAuthority)
+		if err != nil {
+			return kconf.InvalidErrorf("cannot read certificate authority: %w", err)
+		}
+		cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(ca)
+	}
+
+	var user User
+	var expiry string
+	if c.Kubelogin {
+		user.Exec = kubeloginPlugin(c.Issuer, c.ClientID, c.ClientSecret, c.Scope)
+	} else {
+		token, err := c.deviceLogin()
+		if err != nil {
+			return err
+		}
+		user.Token = token
+		if t, ok := tokenExpiry(token); ok {
+			expiry = ", " + describeExpiry(t)
+		}
+	}
+
+	kc := &Kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		CurrentContext: name,
+		Clusters:       []NamedCluster{{Name: name, Cluster: cluster}},
+		Contexts:       []NamedContext{{Name: name, Context: Context{Cluster: name, User: name}}},
+		Users:          []NamedUser{{Name: name, User: user}},
+	}
+	data, err := kc.marshal()
+	if err != nil {
+		return err
+	}
+
+	// the flow waits for the user, the library is locked only to add the result
+	unlock, err := c.Library.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	replace, err := c.addData(data, name)
+	if err != nil {
+		return err
+	}
+	if replace {
+		c.infof("%s -> %s replaced%s\n", name, c.Server, expiry)
+	} else {
+		c.infof("%s -> %s added%s\n", name, c.Server, expiry)
+	}
+	return nil
+}
+
+// deviceLogin returns the ID token of the user who approved the device code on another device
+func (c *Config) deviceLogin() (string, error) {
+	client := &http.Client{Timeout: c.Net.Timeout}
+	var provider oidcDiscovery
+	if err := getJSON(client, strings.TrimSuffix(c.Issuer, "/")+"/.well-known/openid-configuration", &provider); err != nil {
+		return "", networkErrorf("cannot discover OIDC provider %s: %w", c.Issuer, err)
+	}
+	if provider.DeviceAuthorizationEndpoint == "" {
+		return "", kconf.InvalidErrorf("OIDC provider %s does not support the device authorization flow", c.Issuer)
+	}
+
+	form := url.Values{"client_id": {c.ClientID}, "scope": {c.Scope}}
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+	var auth deviceAuthorization
+	if err := postForm(client, provider.DeviceAuthorizationEndpoint, form, &auth); err != nil {
+		return "", networkErrorf("cannot request device code: %w", err)
+	}
+	verification := auth.VerificationURIComplete
+	if verification == "" {
+		verification = auth.VerificationURI
+		if verification == "" {
+			verification = auth.VerificationURL
+		}
+		fmt.Fprintf(os.Stderr, "Open %s and enter the code %s\n", verification, auth.UserCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "Open %s and check the code %s\n", verification, auth.UserCode)
+	}
+
+	interval := defaultDeviceInterval
+	if auth.Interval > 0 {
+		interval = time.Duration(auth.Interval) * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	form = url.Values{"grant_type": {deviceCodeGrant}, "device_code": {auth.DeviceCode}, "client_id": {c.ClientID}}
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+	for {
+		time.Sleep(interval)
+		var tokens oidcTokens
+		err := postForm(client, provider.TokenEndpoint, form, &tokens)
+		switch tokens.Error {
+		case "":
+			if err != nil {
+				return "", networkErrorf("cannot request token: %w", err)
+			}
+			if tokens.IDToken == "" {
+				return "", fmt.Errorf("no ID token issued, is %s in the scopes?", defaultOIDCScope)
+			}
+			return tokens.IDToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += defaultDeviceInterval
+		default:
+			return "", fmt.Errorf("login failed: %s %s", tokens.Error, tokens.ErrorDescription)
+		}
+		if auth.ExpiresIn > 0 && time.Now().After(deadline) {
+			return "", fmt.Errorf("device code expired")
+		}
+	}
+}
+
+// kubeloginPlugin returns the exec plugin of kubelogin running the device authorization flow
+func kubeloginPlugin(issuer, clientID, clientSecret, scope string) *ExecConfig {
+	command := []string{kubeloginCommand, "oidc-login", "get-token", "--oidc-issuer-url=" + issuer, "--oidc-client-id=" + clientID, "--grant-type=device-code"}
+	if clientSecret != "" {
+		command = append(command, "--oidc-client-secret="+clientSecret)
+	}
+	for _, s := range strings.Fields(scope) {
+		// kubelogin always requests openid
+		if s != defaultOIDCScope {
+			command = append(command, "--oidc-extra-scope="+s)
+		}
+	}
+	plugin := execPlugin(command)
+	plugin.APIVersion = betaExecAPIVersion
+	return plugin
+}
+
+// getJSON decodes the response of the GET request
+func getJSON(client *http.Client, target string, v interface{}) error {
+	resp, err := client.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// postForm decodes the response of the form POST request, the OAuth errors are decoded too
+func postForm(client *http.Client, target string, form url.Values, v interface{}) error {
+	resp, err := client.PostForm(target, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response with status %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
-	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
 )
 
 const (
@@ -17,6 +22,18 @@ const (
 	kubeConfigVar                = "KUBECONFIG"
 	confPathVar                  = "KCONF_LIBRARY_PATH"
 	confDirFileMode  os.FileMode = 0755
+	// kubeDefaultConfig is the kubeconfig used when KUBECONFIG is not set, relative to the home directory
+	kubeDefaultConfig = ".kube/config"
+	// kubeDefaultName is the name of the implicit entry of the kubeDefaultConfig
+	kubeDefaultName = "default"
+
+	outputText = "text"
+	outputJSON = "json"
+
+	dryRunUsage  = "Print what would change without touching the filesystem"
+	byNameUsage  = "Resolve the kubeconfig arguments only by name, even the numbers"
+	byIndexUsage = "Resolve the kubeconfig arguments only by index"
+	guardUsage   = "Do not ask for confirmation of the guarded kubeconfigs"
 )
 
 // Config is the program config
@@ -26,6 +43,634 @@ type Config struct {
 	List   bool
 	Remove bool
 	Ops    uint8
+
+	// Command is the name of the operation to perform
+	Command string
+	// Wide enables the extended list output
+	Wide bool
+	// Force overwrites the existing entries
+	Force bool
+	// Copy stores the copies of the kubeconfigs
+	Copy bool
+	// Relative stores the links relative to the library
+	Relative bool
+	// Pattern selects the entries by a glob pattern
+	Pattern string
+	// DryRun prints the changes instead of making them
+	DryRun bool
+	// Debug enables the debug logging
+	Debug bool
+	// Color is the color mode
+	Color string
+	// Output is the output format
+	Output string
+	// Quiet suppresses the informational and error messages
+	Quiet bool
+	// Yes answers yes to all the confirmations
+	Yes bool
+	// Purge deletes the kubeconfig files along with the entries
+	Purge bool
+	// Delete deletes instead of adding
+	Delete bool
+	// Status enables the API server probes
+	Status bool
+	// All selects all the entries
+	All bool
+	// IgnoreCase makes the searches case-insensitive
+	IgnoreCase bool
+	// ServerVersion enables the Kubernetes version queries
+	ServerVersion bool
+	// Tag selects the entries by their key=value tag
+	Tag string
+	// AliasPrefix is the prefix of the shell aliases
+	AliasPrefix string
+	// Names prints the bare entry names
+	Names bool
+	// Current selects the active entry
+	Current bool
+	// ByName resolves the arguments only by name
+	ByName bool
+	// ByIndex resolves the arguments only by index
+	ByIndex bool
+	// Contexts lists the entries having several contexts as entry/context rows
+	Contexts bool
+	// Tree prints the entries as a tree of their hierarchical names
+	Tree bool
+	// Refresh ignores the cached data
+	Refresh bool
+	// OlderThan is the minimum age of the temporary files removed by gc
+	OlderThan time.Duration
+	// Exec is the command line of the exec credential plugin
+	Exec string
+	// User is the name of the kubeconfig user
+	User string
+	// ServiceAccount is the namespace/name of the service account the token is requested for
+	ServiceAccount string
+	// Duration is the validity of the requested token
+	Duration time.Duration
+	// AddAs is the name of the entry added with the requested token
+	AddAs string
+	// Wait is the maximum duration to wait for the approval of the certificate signing request
+	Wait time.Duration
+	// Entry is the name of the entry of the profile
+	Entry string
+	// Context is the context of the profile
+	Context string
+	// Namespace is the namespace of the profile
+	Namespace string
+	// JSONPath is the kubectl JSONPath template of the printed fields
+	JSONPath string
+	// Format is the Go template of the listed entries
+	Format string
+	// Limit is the maximum number of the listed items
+	Limit int
+	// Offset is the number of the listed items skipped
+	Offset int
+	// NoPager prints the list directly to the terminal
+	NoPager bool
+	// Archive is the file of the exported library
+	Archive string
+	// Sanitized strips the credentials from the exported kubeconfig
+	Sanitized bool
+	// UserFrom is the kubeconfig the user comes from
+	UserFrom string
+	// Vars are the variables of the kubeconfig template
+	Vars templateVars
+	// Region is the cloud region
+	Region string
+	// Name is the name of the added kubeconfig
+	Name string
+	// NameTemplate is the Go template of the names of the added kubeconfigs
+	NameTemplate string
+	// Prefix is the prefix of the names of the imported kubeconfigs
+	Prefix string
+	// LocalPort is the local port of the SSH tunnel
+	LocalPort int
+	// Remote is the API server address the SSH tunnel forwards to
+	Remote string
+	// Issuer is the URL of the OpenID provider
+	Issuer string
+	// ClientID is the OAuth client of the login
+	ClientID string
+	// ClientSecret is the secret of the OAuth client, if it's confidential
+	ClientSecret string
+	// Scope are the space separated OAuth scopes of the login
+	Scope string
+	// Server is the API server of the logged kubeconfig
+	Server string
+	// CertificateAuthority is the CA file of the API server
+	CertificateAuthority string
+	// Kubelogin stores the kubelogin exec plugin instead of the token
+	Kubelogin bool
+	// Merge adopts the files of the KUBECONFIG list as one entry
+	Merge bool
+	// Each adopts each file of the KUBECONFIG list
+	Each bool
+	// AzureKubelogin converts the azure auth-providers to the kubelogin exec plugin
+	AzureKubelogin bool
+	// AWSCLI converts the aws-iam-authenticator exec plugins to aws eks get-token
+	AWSCLI bool
+	// Shell is the syntax of the printed shell commands
+	Shell string
+	// Listen is the address the metrics are served on
+	Listen string
+	// Net are the options of the requests sent to the API servers
+	Net netOptions
+	// LibraryPath is the library directory overriding the environment
+	LibraryPath string
+	// Library is the managed library
+	Library *kconf.Library
+	// Settings are the preferences from the library config file
+	Settings *Settings
+
+	args []string
+	// detectedShell caches the syntax of the calling shell
+	detectedShell string
+}
+
+// command describes an operation which can be selected by name
+type command struct {
+	description string
+	flags       func(*Config, *flag.FlagSet)
+	handler     func(*Config, string, []string) error
+	// locked commands change the library and run under its lock
+	locked bool
+	// operation commands take the operation as the first argument, the flags can be anywhere
+	operation bool
+}
+
+// commands returns the operations which can be selected by name
+func commands() map[string]command {
+	return map[string]command{
+		"add": {
+			description: "Add kubeconfig to the library",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfig with the same name")
+				fs.BoolVar(&c.Relative, "relative", false, "Store the link relative to the library directory")
+				fs.BoolVar(&c.Copy, "copy", false, "Store a copy of the kubeconfig in the library")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+				fs.StringVar(&c.Name, "n", "", "Name of the kubeconfig, all the arguments are kubeconfigs")
+				fs.StringVar(&c.NameTemplate, "name-template", "", "Go template of the kubeconfig names over .Name, .Context, .Cluster and .Server, all the arguments are kubeconfigs")
+			},
+			handler: (*Config).addKubeconfig,
+			locked:  true,
+		},
+		"template": {
+			description: "Add kubeconfig rendered from Go template: template render <template> [name]",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				c.Vars = templateVars{}
+				fs.Var(c.Vars, "var", "Variable of the template as key=value, repeatable")
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfig with the same name")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler:   (*Config).manageTemplate,
+			locked:    true,
+			operation: true,
+		},
+		"apply-user": {
+			description: "Replace users of kubeconfigs matching pattern with user of file: apply-user --user-from <file> --to <pattern>",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.UserFrom, "user-from", "", "Kubeconfig the user comes from")
+				fs.StringVar(&c.User, "user", "", "User of the file (default its first user)")
+				fs.StringVar(&c.Pattern, "to", "", "Glob pattern of the kubeconfig names")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).applyUser,
+			locked:  true,
+		},
+		"rotate-cert": {
+			description: "Replace client certificate of kubeconfig with new one signed through CertificateSigningRequest",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.DurationVar(&c.Wait, "wait", defaultCSRWait, "Maximum duration to wait for the approval")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).rotateCert,
+		},
+		"token": {
+			description: "Request short-lived service account token with kubeconfig credentials",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.ServiceAccount, "sa", "", "Service account as namespace/name")
+				fs.DurationVar(&c.Duration, "duration", defaultTokenDuration, "Validity of the token")
+				fs.StringVar(&c.AddAs, "add", "", "Add kubeconfig with the token under the name instead of printing it")
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfig with the same name")
+			},
+			handler: (*Config).mintToken,
+		},
+		"profile": {
+			description: "Manage profiles bundling kubeconfig, context and namespace (create, use, list, delete, export, import)",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Entry, "entry", "", "Kubeconfig of the created profile")
+				fs.StringVar(&c.Context, "context", "", "Context of the created profile (default the current context)")
+				fs.StringVar(&c.Namespace, "namespace", "", "Namespace of the created profile (default the namespace of the context)")
+				fs.BoolVar(&c.Force, "f", false, "Replace the existing profiles")
+				fs.BoolVar(&c.Yes, "yes", false, guardUsage)
+			},
+			handler:   (*Config).manageProfile,
+			locked:    true,
+			operation: true,
+		},
+		"export": {
+			description: "Print kubeconfig, --sanitized strips its credentials for sharing",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Sanitized, "sanitized", false, "Strip the credentials")
+				fs.StringVar(&c.Exec, "exec", "", "Command line of the placeholder exec credential plugin of the sanitized users")
+				fs.StringVar(&c.Archive, "archive", "", "Write all the kubeconfigs with their metadata to the archive file instead, - for stdout")
+			},
+			handler: (*Config).exportKubeconfig,
+		},
+		"import": {
+			description: "Add the kubeconfigs of archive written by export --archive with their metadata: import <archive>",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfigs with the same names")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).importArchive,
+			locked:  true,
+		},
+		"overlay": {
+			description: "Add kubeconfig combining clusters of base kubeconfig with user of file: overlay <base> <user-file> <name>",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.User, "user", "", "User of the file (default its first user)")
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfig with the same name")
+				fs.BoolVar(&c.Refresh, "refresh", false, "Regenerate all the overlay kubeconfigs from their pieces")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).overlayKubeconfig,
+			locked:  true,
+		},
+		"eks": {
+			description: "Add kubeconfigs of the EKS clusters of all the AWS SSO profiles, named <prefix><profile>/<cluster>",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Pattern, "profile", "", "Glob pattern of the AWS profiles")
+				fs.StringVar(&c.Region, "region", "", "AWS region, the one of the profile by default")
+				fs.StringVar(&c.Prefix, "prefix", defaultEKSPrefix, "Prefix of the kubeconfig names")
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfigs with the same name")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).importEKS,
+		},
+		"login": {
+			description: "Add kubeconfig with ID token of device authorization flow: login oidc <name>",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Issuer, "issuer", "", "URL of the OpenID provider")
+				fs.StringVar(&c.ClientID, "client-id", "", "OAuth client ID")
+				fs.StringVar(&c.ClientSecret, "client-secret", "", "OAuth client secret, if the client is confidential")
+				fs.StringVar(&c.Scope, "scope", defaultOIDCScope, "Space separated OAuth scopes")
+				fs.StringVar(&c.Server, "server", "", "URL of the API server")
+				fs.StringVar(&c.CertificateAuthority, "certificate-authority", "", "CA file of the API server")
+				fs.BoolVar(&c.Kubelogin, "kubelogin", false, "Store kubelogin exec plugin refreshing the token instead of logging in now")
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfig with the same name")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler:   (*Config).login,
+			operation: true,
+		},
+		"adopt": {
+			description: "Add the active kubeconfig, named after its current context if no name is given",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Force, "f", false, "Replace the kubeconfig with the same name")
+				fs.BoolVar(&c.Relative, "relative", false, "Store the link relative to the library directory")
+				fs.BoolVar(&c.Copy, "copy", false, "Store a copy of the kubeconfig in the library")
+				fs.BoolVar(&c.Merge, "merge", false, "Add the files of a KUBECONFIG list merged as one kubeconfig")
+				fs.BoolVar(&c.Each, "each", false, "Add each file of a KUBECONFIG list")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).adoptKubeconfig,
+			locked:  true,
+		},
+		"set": {
+			description: "Set current kubeconfig",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Yes, "yes", false, guardUsage)
+				fs.BoolVar(&c.ByName, "by-name", c.ByName, byNameUsage)
+				fs.BoolVar(&c.ByIndex, "by-index", c.ByIndex, byIndexUsage)
+			},
+			handler: (*Config).setKubeconfig,
+			locked:  true,
+		},
+		"unset": {
+			description: "Print the shell commands unsetting KUBECONFIG and the environment of the active kubeconfig",
+			handler:     (*Config).unsetKubeconfig,
+		},
+		"status": {
+			description: "Print the active kubeconfig with its file, context, namespace and credentials",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Status, "ping", false, "Probe the API server")
+			},
+			handler: (*Config).printStatus,
+		},
+		"current": {
+			description: "Print the name of the active kubeconfig",
+			handler:     (*Config).printCurrent,
+		},
+		"list": {
+			description: "List all kubeconfigs from the library",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Wide, "wide", false, "Show the API servers and when the kubeconfigs were added and last used")
+				fs.BoolVar(&c.Status, "status", false, "Show the statuses of the API servers")
+				fs.BoolVar(&c.ServerVersion, "server-version", false, "Show the Kubernetes versions of the API servers")
+				fs.BoolVar(&c.Refresh, "refresh", false, "Ignore the cached cluster data")
+				fs.BoolVar(&c.Names, "names", false, "Print only the names, one per line")
+				fs.BoolVar(&c.Tree, "tree", false, "Print the hierarchical names as a tree")
+				fs.BoolVar(&c.Contexts, "contexts", false, "List the kubeconfigs having several contexts as entry/context rows")
+				fs.IntVar(&c.Limit, "limit", 0, "Maximum number of the listed kubeconfigs, all if 0")
+				fs.IntVar(&c.Offset, "offset", 0, "Number of the kubeconfigs skipped before the listed ones")
+				fs.BoolVar(&c.NoPager, "no-pager", false, "Don't send the list to $PAGER")
+				fs.StringVar(&c.Format, "format", "", "Print the kubeconfigs with the Go template, e.g. '{{.Name}} {{.Server}}'")
+			},
+			handler: (*Config).listKubeconfigs,
+		},
+		"remove": {
+			description: "Remove kubeconfig from the library",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Pattern, "pattern", "", "Remove all kubeconfigs with names matching the glob pattern")
+				fs.BoolVar(&c.Force, "f", false, "Remove the protected kubeconfigs too")
+				fs.BoolVar(&c.Yes, "yes", false, "Do not ask for confirmation")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+				fs.BoolVar(&c.ByName, "by-name", c.ByName, byNameUsage)
+				fs.BoolVar(&c.ByIndex, "by-index", c.ByIndex, byIndexUsage)
+				fs.BoolVar(&c.Current, "current", false, "Remove the active kubeconfig and print the shell commands switching to the default one or unsetting KUBECONFIG")
+			},
+			handler: (*Config).removeKubeconfig,
+			locked:  true,
+		},
+		"clear": {
+			description: "Remove all kubeconfigs from the library",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Yes, "yes", false, "Do not ask for confirmation")
+				fs.BoolVar(&c.Purge, "purge", false, "Delete the kubeconfig files the library points to as well")
+				fs.BoolVar(&c.Force, "f", false, "Remove the protected kubeconfigs too")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).clearLibrary,
+			locked:  true,
+		},
+		"trash": {
+			description: "List the kubeconfigs moved to trash, restore them: trash restore <name>..., or empty the trash: trash empty",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Yes, "yes", false, "Do not ask for confirmation")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler:   (*Config).trash,
+			locked:    true,
+			operation: true,
+		},
+		"update": {
+			description: "Point kubeconfig to another file",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Relative, "relative", false, "Store the link relative to the library directory")
+				fs.BoolVar(&c.Copy, "copy", false, "Store a copy of the kubeconfig in the library")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).updateKubeconfig,
+			locked:  true,
+		},
+		"verify": {
+			description: "Check whether the kubeconfigs changed since they were added",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.All, "all", false, "Verify all the kubeconfigs")
+			},
+			handler: (*Config).verifyKubeconfigs,
+		},
+		"validate": {
+			description: "Check that the kubeconfigs parse and the files they reference exist",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.All, "all", false, "Validate all the kubeconfigs")
+			},
+			handler: (*Config).validateKubeconfigs,
+		},
+		"lint": {
+			description: "Report deprecated authentication and insecure settings of kubeconfigs",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.All, "all", false, "Lint all the kubeconfigs")
+			},
+			handler: (*Config).lintKubeconfigs,
+		},
+		"history": {
+			description: "List the previous contents of kubeconfig, or the last switches without name (!N switches again to the Nth)",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.IntVar(&c.Limit, "n", defaultRecentSwitches, "Number of the listed switches")
+				fs.BoolVar(&c.Yes, "yes", false, guardUsage)
+			},
+			handler: (*Config).printHistory,
+			locked:  true,
+		},
+		"rollback": {
+			description: "Point kubeconfig to one of its previous contents",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).rollbackKubeconfig,
+			locked:  true,
+		},
+		"rewrite-auth": {
+			description: "Replace static credentials of kubeconfig with exec credential plugin",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Exec, "exec", "", "Command line of the exec credential plugin")
+				fs.StringVar(&c.User, "user", "", "User to rewrite (default the user of the current context)")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).rewriteAuth,
+			locked:  true,
+		},
+		"convert": {
+			description: "Rewrite deprecated authentication stanzas of kubeconfigs to their exec plugins",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.AzureKubelogin, "azure-kubelogin", false, "Convert the azure auth-providers to kubelogin")
+				fs.BoolVar(&c.AWSCLI, "aws-cli", false, "Convert the aws-iam-authenticator exec plugins and the v1alpha1 aws ones to aws eks get-token")
+				fs.BoolVar(&c.All, "all", false, "Convert all the kubeconfigs")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).convertKubeconfigs,
+			locked:  true,
+		},
+		"tunnel": {
+			description: "Reach API server of kubeconfig through SSH tunnel via jump host",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Remove the tunnel")
+				fs.IntVar(&c.LocalPort, "local-port", 0, "Local port of the tunnel (default a free port)")
+				fs.StringVar(&c.Remote, "remote", "", "API server host:port as seen from the jump host (default the server of the kubeconfig)")
+			},
+			handler: (*Config).tunnelKubeconfig,
+			locked:  true,
+		},
+		"proxy": {
+			description: "Reach API server of kubeconfig through proxy (http, https or socks5 URL)",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Remove the proxy")
+			},
+			handler: (*Config).proxyKubeconfig,
+			locked:  true,
+		},
+		"has": {
+			description: "Exit with 0 if kubeconfig is in the library, 1 otherwise, without output",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.ByName, "by-name", c.ByName, byNameUsage)
+				fs.BoolVar(&c.ByIndex, "by-index", c.ByIndex, byIndexUsage)
+			},
+			handler: (*Config).hasKubeconfig,
+		},
+		"get": {
+			description: "Print field of kubeconfig (context, cluster, user, namespace, server or path like user.exec.command): get <name> <field>",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.JSONPath, "jsonpath", "", "kubectl JSONPath template of the fields instead, e.g. '{.clusters[*].name}'")
+				fs.BoolVar(&c.ByName, "by-name", c.ByName, byNameUsage)
+				fs.BoolVar(&c.ByIndex, "by-index", c.ByIndex, byIndexUsage)
+			},
+			handler: (*Config).getField,
+		},
+		"patch": {
+			description: "Apply strategic merge or JSON patch file to working copy of kubeconfig (- for stdin), print it without file",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Remove the patch")
+			},
+			handler: (*Config).patchKubeconfig,
+			locked:  true,
+		},
+		"exec": {
+			description: "Run command with kubeconfig: kconf exec <name> -- <command> [args...]",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Yes, "yes", false, guardUsage)
+			},
+			handler: (*Config).execKubeconfig,
+		},
+		"protect": {
+			description: "Protect kubeconfig from removal",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Remove the protection")
+			},
+			handler: (*Config).protectKubeconfig,
+			locked:  true,
+		},
+		"default": {
+			description: "Mark kubeconfig set when none is given, print the default one",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Unset the default kubeconfig")
+			},
+			handler: (*Config).defaultKubeconfig,
+			locked:  true,
+		},
+		"tag": {
+			description: "Set key=value tags of kubeconfig",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Delete the tags with the given keys")
+			},
+			handler: (*Config).tagKubeconfig,
+			locked:  true,
+		},
+		"env": {
+			description: "Set NAME=value environment variables exported along with kubeconfig, list them without variables",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Delete the variables with the given names")
+			},
+			handler: (*Config).envKubeconfig,
+			locked:  true,
+		},
+		"shell": {
+			description: "Start shell with kubeconfig and its environment variables: shell <name>",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Yes, "yes", false, guardUsage)
+			},
+			handler: (*Config).startShell,
+		},
+		"aliases": {
+			description: "Print the shell aliases setting the kubeconfigs",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Tag, "tag", "", "Only the kubeconfigs with the key=value tag")
+				fs.StringVar(&c.AliasPrefix, "prefix", "k", "Prefix of the alias names")
+			},
+			handler: (*Config).printShellAliases,
+		},
+		"export-script": {
+			description: "Print the shell script of aliases equivalent to the library, usable without kconf",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Tag, "tag", "", "Only the kubeconfigs with the key=value tag")
+				fs.StringVar(&c.AliasPrefix, "prefix", "k", "Prefix of the alias names")
+			},
+			handler: (*Config).printExportScript,
+		},
+		"k9s": {
+			description: "Run k9s with kubeconfig, selected interactively if no name is given: k9s [name] [-- k9s args...]",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Yes, "yes", false, guardUsage)
+			},
+			handler: (*Config).launchK9s,
+		},
+		"run": {
+			description: "Run tool with kubeconfig using its launcher from the config: run <tool> [name] [-- args...]",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Yes, "yes", false, guardUsage)
+			},
+			handler: (*Config).runTool,
+		},
+		"grep": {
+			description: "Search the kubeconfigs for the regular expression",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.IgnoreCase, "i", false, "Ignore case")
+			},
+			handler: (*Config).grepKubeconfigs,
+		},
+		"ping": {
+			description: "Check the reachability of the API servers and the validity of the credentials",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.All, "all", false, "Ping all the kubeconfigs")
+			},
+			handler: (*Config).pingKubeconfigs,
+		},
+		"watch": {
+			description: "Add the kubeconfigs dropped in the directory until interrupted",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Force, "f", false, "Replace the existing kubeconfigs")
+			},
+			handler: (*Config).watchDirectory,
+		},
+		"daemon": {
+			description: "Install the user service running watch on the directory to add the dropped kubeconfigs: daemon install <dir>, or uninstall it: daemon uninstall",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Force, "f", false, "Replace the existing kubeconfigs")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler:   (*Config).daemon,
+			operation: true,
+		},
+		"serve": {
+			description: "Serve the library metrics for Prometheus until interrupted",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.StringVar(&c.Listen, "listen", defaultListenAddress, "Address to listen on")
+			},
+			handler: (*Config).serveMetrics,
+		},
+		"gc": {
+			description: "Remove the unused kubeconfig copies and the stale temporary files",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.DurationVar(&c.OlderThan, "older-than", defaultGCAge, "Minimum age of the removed temporary files")
+				fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, dryRunUsage)
+			},
+			handler: (*Config).collectGarbage,
+			locked:  true,
+		},
+		"credential": {
+			description: "Print the ExecCredential of the named credential (kubectl exec plugin)",
+			handler:     (*Config).printCredential,
+		},
+		"cache": {
+			description: "Manage the cache of the cluster data (clear)",
+			handler:     (*Config).manageCache,
+			locked:      true,
+		},
+		"version": {
+			description: "Print the version and build information",
+			handler:     (*Config).printVersion,
+		},
+		"alias": {
+			description: "Add an alternative name to kubeconfig",
+			flags: func(c *Config, fs *flag.FlagSet) {
+				fs.BoolVar(&c.Delete, "d", false, "Delete the alias")
+			},
+			handler: (*Config).aliasKubeconfig,
+			locked:  true,
+		},
+	}
 }
 
 // Flags registers and parses the program flags
@@ -34,187 +679,902 @@ func (c *Config) Flags() {
 	flag.BoolVar(&c.Set, "s", false, "Set current kubeconfig")
 	flag.BoolVar(&c.List, "l", false, "List all kubeconfigs from the library")
 	flag.BoolVar(&c.Remove, "r", false, "Remove kubeconfig from the library")
+	flag.BoolVar(&c.DryRun, "dry-run", false, dryRunUsage)
+	flag.BoolVar(&c.Quiet, "q", false, "Quiet mode: no messages, the result is given by the exit code")
+	flag.BoolVar(&c.Quiet, "quiet", false, "Same as -q")
+	flag.BoolVar(&c.Debug, "v", false, "Log the debug information to stderr")
+	flag.BoolVar(&c.Debug, "debug", false, "Same as -v")
+	flag.StringVar(&c.Color, "color", colorAuto, "Color the output: auto, always or never (auto honors NO_COLOR)")
+	flag.DurationVar(&c.Net.Timeout, "timeout", defaultProbeTimeout, "Timeout of the requests to the API servers")
+	flag.IntVar(&c.Net.Retries, "retries", 0, "Number of retries of the failed requests to the API servers")
+	flag.IntVar(&c.Net.Workers, "workers", defaultProbeWorkers, "Number of the API servers queried concurrently")
+	flag.StringVar(&c.Output, "o", outputText, "Output format: text or json")
+	flag.StringVar(&c.Output, "output", outputText, "Same as -o")
+	flag.BoolVar(&c.ByName, "by-name", false, byNameUsage)
+	flag.BoolVar(&c.ByIndex, "by-index", false, byIndexUsage)
+	flag.StringVar(&c.LibraryPath, "library", "", "Library directory, overrides "+confPathVar)
+	flag.StringVar(&c.Shell, "shell", shellAuto, "Syntax of the printed shell commands: auto, posix, fish or powershell (auto detects the calling shell)")
+	flag.Usage = usage
 	flag.Parse()
+	c.args = flag.Args()
+
+	if c.Debug {
+		debugLog.SetOutput(os.Stderr)
+	}
 
 	if c.Add {
 		c.Ops |= 1
+		c.Command = "add"
 	}
 	if c.Set {
 		c.Ops |= 2
+		c.Command = "set"
 	}
 	if c.List {
 		c.Ops |= 4
+		c.Command = "list"
 	}
 	if c.Remove {
 		c.Ops |= 8
+		c.Command = "remove"
+	}
+
+	if c.Ops != 0 {
+		return
+	}
+
+	// command given by name
+	if len(c.args) > 0 {
+		if cmd, ok := commands()[c.args[0]]; ok {
+			c.Command = c.args[0]
+			fs := flag.NewFlagSet(c.Command, flag.ExitOnError)
+			if cmd.flags != nil {
+				cmd.flags(c, fs)
+			}
+			// ExitOnError: parsing errors terminate the program
+			_ = fs.Parse(c.args[1:])
+			c.args = fs.Args()
+			if cmd.operation {
+				// the flags can follow the operation and its arguments
+				var positional []string
+				for len(c.args) > 0 {
+					positional = append(positional, c.args[0])
+					_ = fs.Parse(c.args[1:])
+					c.args = fs.Args()
+				}
+				c.args = positional
+			}
+			return
+		}
 	}
 
 	// default cases
 
 	// no flags specified
-	if c.Ops == 0 {
-		switch len(os.Args) {
-		// no args: list
-		case 1:
-			c.List = true
-		// 1 arg: set
-		case 2:
-			c.Set = true
-		// 2 args: add
-		case 3:
-			c.Add = true
-		}
+	switch len(c.args) {
+	// no args: list
+	case 0:
+		c.Command = "list"
+	// 1 arg: set, e.g. kconf 3 to switch by the index
+	case 1:
+		c.Command = "set"
+	// 2 args: add
+	case 2:
+		c.Command = "add"
 	}
 }
 
-// Args returns the program argumets without the program name and flags
+// Args returns the program argumets without the program name, flags and command
 func (c *Config) Args() []string {
-	if c.Ops == 0 {
-		return os.Args[1:]
-	}
-	return os.Args[2:]
+	return c.args
 }
 
 // Validate checks the flags for conflicts
-func (c *Config) Validate() bool {
+func (c *Config) Validate() error {
 	switch c.Ops {
 	case 0, 1, 2, 4, 8:
-		return true
+	default:
+		return kconf.InvalidErrorf("conflicting operations")
 	}
-	return false
-}
 
-// Handler returns the handler function for the operation specified by the flag
-func (c *Config) Handler() func(string, []string) error {
-	if c.Add {
-		return addKubeconfig
+	switch c.Output {
+	case outputText, outputJSON:
+	default:
+		return kconf.InvalidErrorf("unknown output format: %q", c.Output)
 	}
-	if c.Set {
-		return setKubeconfig
+
+	switch c.Color {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		return kconf.InvalidErrorf("unknown color mode: %q", c.Color)
 	}
-	if c.List {
-		return listKubeconfigs
+
+	if c.ByName && c.ByIndex {
+		return kconf.InvalidErrorf("conflicting --by-name and --by-index")
 	}
-	if c.Remove {
-		return removeKubeconfig
+
+	switch c.Shell {
+	case shellAuto, shellPOSIX, shellFish, shellPowerShell:
+	default:
+		return kconf.InvalidErrorf("unknown shell: %q", c.Shell)
+	}
+	return nil
+}
+
+// Handler returns the handler function for the operation specified by the flag or command
+func (c *Config) Handler() func(string, []string) error {
+	if cmd, ok := commands()[c.Command]; ok {
+		return func(configPath string, args []string) error {
+			if cmd.locked {
+				unlock, err := c.Library.Lock()
+				if err != nil {
+					return err
+				}
+				defer unlock()
+			}
+			return cmd.handler(c, configPath, args)
+		}
 	}
 
 	return func(string, []string) error { return nil }
 }
 
+// usage prints the program usage
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [args]\n\nCommands:\n", path.Base(os.Args[0]))
+	cmds := commands()
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-10s %s\n", name, cmds[name].description)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
 /*************
     Handlers
 **************/
 
-func addKubeconfig(configPath string, args []string) error {
+func (c *Config) addKubeconfig(configPath string, args []string) error {
 	if len(args) < 1 {
-		return fmt.Errorf("not enough arguments")
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+	if c.Name != "" || c.NameTemplate != "" {
+		return c.addNamed(configPath, args)
+	}
+	if isGKEGateway(args[0]) {
+		var name string
+		if len(args) > 1 {
+			name = args[1]
+		}
+		return c.addGKEGateway(args[0], name)
 	}
 
-	var file, slink, symlink string
+	var slink string
+	file, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
 
 	switch len(args) {
 	case 1:
-		slink = strings.Split(path.Base(args[0]), ".")[0]
+		slink = defaultName(file)
+		if !c.Force {
+			slink = freeName(configPath, slink, file)
+		}
 	case 2:
 		fallthrough
 	default:
 		slink = args[1]
 	}
-	file = args[0]
-	symlink = configPath + "/" + slink
+	return c.addFile(file, slink)
+}
 
-	file, err := filepath.Abs(file)
+// addFile adds the kubeconfig file under the name
+func (c *Config) addFile(file, slink string) error {
+	replace, err := c.Library.Add(file, slink, kconf.AddOptions{
+		LinkOptions: c.linkOptions(),
+		Force:       c.Force,
+		DryRun:      c.DryRun,
+	})
 	if err != nil {
 		return err
 	}
 
-	if !exists(file) {
-		return fmt.Errorf("kubeconfig not found: %s", file)
+	switch {
+	case c.DryRun && replace:
+		fmt.Printf("%s -> %s would be replaced\n", slink, file)
+	case c.DryRun:
+		fmt.Printf("%s -> %s would be added\n", slink, file)
+	case replace:
+		c.infof("%s -> %s replaced\n", slink, file)
+	default:
+		c.infof("%s -> %s added\n", slink, file)
 	}
+	return nil
+}
 
-	if exists(symlink) {
-		return fmt.Errorf("kubeconfig already exists: %q", slink)
+// nameData is given to the name template of the added kubeconfigs
+type nameData struct {
+	// Name is the default name: the file name without its kubeconfig extension
+	Name    string
+	Context string
+	Cluster string
+	Server  string
+}
+
+// addNamed adds the kubeconfig sources named by the -n flag or by the name template, the templated names get suffixes on collisions
+func (c *Config) addNamed(configPath string, args []string) error {
+	if c.Name != "" && (c.NameTemplate != "" || len(args) > 1) {
+		return kconf.InvalidErrorf("-n names a single kubeconfig, use --name-template for several ones")
+	}
+	var tmpl *template.Template
+	if c.NameTemplate != "" {
+		var err error
+		if tmpl, err = template.New("name").Option("missingkey=error").Parse(c.NameTemplate); err != nil {
+			return kconf.InvalidErrorf("invalid name template: %w", err)
+		}
 	}
 
-	if err = os.Symlink(file, symlink); err != nil {
-		return err
+	for _, source := range args {
+		if isGKEGateway(source) && tmpl == nil {
+			if err := c.addGKEGateway(source, c.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		file, err := filepath.Abs(source)
+		if err != nil {
+			return err
+		}
+		name := c.Name
+		if tmpl != nil {
+			if name, err = templateName(tmpl, file); err != nil {
+				return err
+			}
+			if !c.Force {
+				name = freeName(configPath, name, file)
+			}
+		}
+		if err = c.addFile(file, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateName returns the name of the kubeconfig file given by the template
+func templateName(tmpl *template.Template, file string) (string, error) {
+	kc, err := loadKubeconfig(file)
+	if err != nil {
+		return "", kconf.InvalidErrorf("cannot parse %s: %w", file, err)
+	}
+	data := nameData{Name: defaultName(file), Context: kc.CurrentContext}
+	if ctx := kc.context(kc.CurrentContext); ctx != nil {
+		data.Cluster = ctx.Cluster
+	}
+	if cluster := kc.currentCluster(); cluster != nil {
+		data.Server = cluster.Server
 	}
 
-	fmt.Printf("%s -> %s added\n", slink, file)
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", kconf.InvalidErrorf("cannot name %s: %w", file, err)
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", kconf.InvalidErrorf("cannot name %s: empty name", file)
+	}
+	return name, nil
+}
 
-	return nil
+// kubeconfigExts are the extensions stripped from the file names to name the kubeconfigs
+var kubeconfigExts = []string{".yaml", ".yml", ".kubeconfig", ".kubecfg", ".conf", ".config", ".json"}
+
+// defaultName returns the name of the kubeconfig file added without name: its base name without a kubeconfig extension,
+// the other dots are kept (prod.eu.yaml is prod.eu)
+func defaultName(file string) string {
+	name := filepath.Base(file)
+	ext := filepath.Ext(name)
+	for _, known := range kubeconfigExts {
+		if strings.EqualFold(ext, known) && len(name) > len(ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
 }
 
-func listKubeconfigs(configPath string, args []string) error {
-	files, err := listSymDir(configPath)
+// freeName returns the name suffixed with -2, -3... while another kubeconfig file has it.
+// The name of the entry already linked to the file is returned as is.
+func freeName(configPath, name, file string) string {
+	real := kconf.Target(file)
+	candidate := name
+	for i := 2; ; i++ {
+		linkPath := path.Join(configPath, candidate)
+		if _, err := os.Lstat(linkPath); err != nil || kconf.Target(linkPath) == real {
+			if candidate != name {
+				debugf("%q taken, %s named %q", name, file, candidate)
+			}
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+// matchEntries returns the entries whose names match the glob pattern, at least one
+func matchEntries(entries []*kconf.Entry, pattern string) ([]*kconf.Entry, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, kconf.InvalidErrorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	var matched []*kconf.Entry
+	for _, e := range entries {
+		// error checked above
+		if ok, _ := path.Match(pattern, e.Name); ok {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, kconf.NotFoundErrorf("no kubeconfigs match %q", pattern)
+	}
+	return matched, nil
+}
+
+// addData adds the kubeconfig content having no file of its own, it's stored in the library like in the copy mode.
+// Returns true if an existing entry was replaced.
+func (c *Config) addData(data []byte, name string) (bool, error) {
+	tmp, err := os.CreateTemp("", "kconf-*.yaml")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+
+	opts := c.linkOptions()
+	opts.Copy = true
+	return c.Library.Add(tmp.Name(), name, kconf.AddOptions{LinkOptions: opts, Force: c.Force})
+}
+
+func (c *Config) makeKubeconfig(configPath string, args []string, result func(*kconf.Entry, *kconf.Metadata) error) error {
+	if len(args) == 0 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+
+	entries, meta, err := c.Library.Entries()
 	if err != nil {
 		return err
 	}
 
-	currKubeConfig := os.Getenv(kubeConfigVar)
+	e, err := c.findEntry(entries, args[0])
+	if err != nil {
+		return err
+	}
+	return result(e, meta)
+}
+
+// findEntry returns the entry given by its index or name, only by one of them with --by-index or --by-name.
+// The name takes precedence over the index, with a warning if they are different entries.
+func (c *Config) findEntry(entries []*kconf.Entry, arg string) (*kconf.Entry, error) {
+	var e *kconf.Entry
+	var err error
+	switch {
+	case c.ByIndex:
+		e, err = kconf.FindIndex(entries, arg)
+	case c.ByName:
+		if e, err = kconf.Match(entries, arg, c.Settings.IgnoreCase); err != nil {
+			err = kconf.EntryError(arg, err)
+		}
+	default:
+		e, err = kconf.Find(entries, arg, c.Settings.IgnoreCase)
+		if err == nil && e.Name == arg {
+			if other, ierr := kconf.FindIndex(entries, arg); ierr == nil && other != e && !c.Quiet {
+				fmt.Fprintf(os.Stderr, "%q is the name of a kubeconfig and the index of %q, using the name (--by-index selects by index)\n", arg, other.Name)
+			}
+		}
+	}
+	if err == nil && e.Name != arg {
+		debugf("%q resolved to %q", arg, e.Name)
+	}
+	return e, err
+}
 
-	var star string
-	for i, file := range files {
-		if path.Join(configPath, file.Name()) == currKubeConfig {
-			star = "* "
+// selectEntries returns the entries given by the selections of the arguments (indexes, ranges and names)
+func (c *Config) selectEntries(entries []*kconf.Entry, args []string) ([]*kconf.Entry, error) {
+	var res []*kconf.Entry
+	seen := map[string]bool{}
+	for _, arg := range args {
+		var selected []*kconf.Entry
+		var err error
+		if c.ByIndex || c.ByName {
+			// no lists nor ranges: the argument is a single index or name
+			var e *kconf.Entry
+			e, err = c.findEntry(entries, arg)
+			selected = []*kconf.Entry{e}
 		} else {
-			star = "  "
+			selected, err = kconf.Select(entries, arg, c.Settings.IgnoreCase)
+		}
+		if err != nil {
+			return nil, err
+		}
+		debugf("%q selected %d kubeconfigs", arg, len(selected))
+		for _, e := range selected {
+			if !seen[e.Name] {
+				seen[e.Name] = true
+				res = append(res, e)
+			}
 		}
-		fmt.Printf("%s%d) %s\n", star, i+1, file.Name())
 	}
-	return nil
+	return res, nil
 }
 
-func makeKubeconfig(configPath string, args []string, result func(string) error) error {
+// hasEntry returns true if the name is the exact name of an entry
+func hasEntry(entries []*kconf.Entry, name string) bool {
+	for _, e := range entries {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKubeconfig answers with the exit code whether the entry exists
+func (c *Config) hasKubeconfig(configPath string, args []string) error {
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the kubeconfig name")
+	}
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	if _, err = c.findEntry(entries, args[0]); errors.Is(err, kconf.ErrNotFound) {
+		return &codeError{code: exitFailure, err: err, silent: true}
+	}
+	return err
+}
+
+func (c *Config) setKubeconfig(configPath string, args []string) error {
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
 	if len(args) == 0 {
-		return fmt.Errorf("not enough arguments")
+		if meta.Default == "" {
+			return kconf.InvalidErrorf("not enough arguments and no default kubeconfig")
+		}
+		args = []string{meta.Default}
+	}
+	previous := args[0] == "-"
+	if previous {
+		prev := loadState(configPath).Previous
+		if prev == "" {
+			return kconf.NotFoundErrorf("no previous kubeconfig")
+		}
+		args = []string{prev}
+	}
+
+	if implicit := kubeDefaultEntry(entries); implicit != nil && args[0] == implicit.Name {
+		return c.output(implicit.Path)
 	}
-	files, err := listSymDir(configPath)
+
+	find := c.findContext
+	if previous {
+		// the recorded name, not another entry matching it
+		find = findRecorded
+	}
+	e, context, err := find(entries, args[0])
 	if err != nil {
+		return err
+	}
+	return c.activate(configPath, e, meta, "", context)
+}
+
+// findContext returns the entry given by its index or name like findEntry,
+// or the entry and the context given by the entry/context name of one of its contexts
+func (c *Config) findContext(entries []*kconf.Entry, arg string) (*kconf.Entry, string, error) {
+	e, err := c.findEntry(entries, arg)
+	if err == nil || !errors.Is(err, kconf.ErrNotFound) || c.ByIndex {
+		return e, "", err
+	}
+	// both the entry and the context names can have slashes, the longest entry name wins
+	for i := strings.LastIndex(arg, "/"); i > 0; i = strings.LastIndex(arg[:i], "/") {
+		name, context := arg[:i], arg[i+1:]
+		if !hasEntry(entries, name) || context == "" {
+			continue
+		}
+		entry, _ := kconf.FindName(entries, name)
+		kc, kerr := loadKubeconfig(entry.Path)
+		if kerr == nil && kc.context(context) != nil {
+			return entry, context, nil
+		}
+	}
+	return nil, "", err
+}
+
+// findRecorded returns the entry and the context of the entry/context name recorded by kconf, matching exactly
+func findRecorded(entries []*kconf.Entry, name string) (*kconf.Entry, string, error) {
+	if e, err := kconf.FindName(entries, name); err == nil {
+		return e, "", nil
+	}
+	for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name[:i], "/") {
+		e, err := kconf.FindName(entries, name[:i])
+		if err != nil {
+			continue
+		}
+		if kc, err := loadKubeconfig(e.Path); err == nil && kc.context(name[i+1:]) != nil {
+			return e, name[i+1:], nil
+		}
+	}
+	return nil, "", kconf.EntryError(name, kconf.NotFoundErrorf("kubeconfig not found: %q", name))
+}
+
+// activate prints the shell command setting KUBECONFIG to the entry, with the context and namespace of the named profile
+// or the given context if any. The switch is recorded in the recent ones.
+func (c *Config) activate(configPath string, e *kconf.Entry, meta *kconf.Metadata, profile, context string) error {
+	if err := c.guard(e); err != nil {
+		return err
+	}
+
+	file, _, err := c.kubeconfigFile(configPath, e)
+	if err != nil {
+		return err
+	}
+	p := meta.Profiles[profile]
+	if context != "" {
+		p = &kconf.Profile{Entry: e.Name, Context: context}
+	}
+	if p != nil && (p.Context != "" || p.Namespace != "") {
+		if file, err = applyProfile(configPath, e, file, p, workVariant(profile, context)); err != nil {
+			return err
+		}
+	}
+	// the tunnel of the kubeconfig switched from is not needed anymore
+	if prev := workEntry(configPath, os.Getenv(kubeConfigVar)); prev != "" && prev != e.Name {
+		if pm, ok := meta.Entries[prev]; ok && pm.Tunnel != nil {
+			c.closeTunnel(configPath, prev, pm.Tunnel)
+		}
+	}
+
+	// the variables of the kubeconfig switched from don't leak to the new one
+	stale := staleEnv(loadState(configPath), meta, e)
+
+	e.Meta.LastUsed = time.Now()
+	e.Meta.Switches++
+	meta.Recent = append([]*kconf.Switch{{Time: e.Meta.LastUsed, Entry: e.Name, Profile: profile, Context: context}}, meta.Recent...)
+	if len(meta.Recent) > maxRecentSwitches {
+		meta.Recent = meta.Recent[:maxRecentSwitches]
+	}
+	if err = c.Library.Save(meta); err != nil {
+		return err
+	}
+	if err = recordActive(configPath, e, profile, context, file); err != nil {
+		return err
+	}
+	c.notify(e, context)
+	for _, name := range stale {
+		fmt.Println(c.unsetCommand(name))
+	}
+	return c.output(file, entryEnv(e.Meta)...)
+}
+
+// kubeDefaultEntry returns the implicit entry of the standard kubeconfig when it exists
+// and the library has neither an entry of the same name nor one pointing to it (nil otherwise)
+func kubeDefaultEntry(entries []*kconf.Entry) *kconf.Entry {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	file := filepath.Join(home, kubeDefaultConfig)
+	if !exists(file) {
+		return nil
+	}
+
+	real := kconf.Target(file)
+	for _, e := range entries {
+		if e.Name == kubeDefaultName || kconf.Target(e.Path) == real {
+			return nil
+		}
+	}
+	return &kconf.Entry{Name: kubeDefaultName, Path: file, Meta: &kconf.EntryMeta{}}
+}
+
+func (c *Config) removeKubeconfig(configPath string, args []string) error {
+	if c.Pattern != "" {
+		return c.removeByPattern(configPath)
+	}
+	if c.Current {
+		return c.removeCurrent(configPath)
+	}
+
+	if len(args) == 0 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	// resolve all the arguments before removing anything
+	selected, err := c.selectEntries(entries, args)
+	if err != nil {
+		return err
+	}
+	exact := true
+	for _, arg := range args {
+		exact = exact && hasEntry(entries, arg)
+	}
+
+	if err = c.checkProtected(selected); err != nil {
+		return err
+	}
+
+	if c.DryRun {
+		for _, e := range selected {
+			fmt.Printf("%s -> %s would be removed\n", e.Name, kconf.Target(e.Path))
+		}
+		return nil
+	}
+
+	// the indexes and partial names easily select another entry than expected
+	if !exact && !c.Yes && isTerminal(os.Stdin) {
+		for _, e := range selected {
+			fmt.Fprintf(os.Stderr, "%d) %s -> %s\n", e.Meta.Index, e.Name, kconf.Target(e.Path))
+		}
+		if !confirm(fmt.Sprintf("Remove %d kubeconfigs?", len(selected))) {
+			return fmt.Errorf("aborted")
+		}
+	}
+	return c.removeEntries(selected, meta)
+}
+
+// removeCurrent removes the active entry after confirmation and prints the shell commands switching to the default entry,
+// unsetting KUBECONFIG without default. The messages go to stderr: stdout is evaluated by the shell.
+func (c *Config) removeCurrent(configPath string) error {
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	e := activeEntry(configPath, entries)
+	if e == nil {
+		c.hintOutside(e)
+		return kconf.NotFoundErrorf("no active kubeconfig")
+	}
+	if err = c.checkProtected([]*kconf.Entry{e}); err != nil {
+		return err
+	}
+	if c.DryRun {
+		fmt.Fprintf(os.Stderr, "%s -> %s would be removed\n", e.Name, kconf.Target(e.Path))
+		return nil
+	}
+	if !c.Yes && isTerminal(os.Stdin) && !confirm(fmt.Sprintf("Remove active kubeconfig %s -> %s?", e.Name, kconf.Target(e.Path))) {
+		return fmt.Errorf("aborted")
+	}
+
+	file, err := c.Library.Remove(e, meta)
+	if err != nil {
+		return err
+	}
+	removeWorkCopies(configPath, e.Name)
+	if err = c.Library.Save(meta); err != nil {
+		return err
+	}
+	if !c.Quiet {
+		fmt.Fprintf(os.Stderr, "%s -> %s removed\n", e.Name, file)
+	}
+
+	if meta.Default != "" && meta.Default != e.Name {
+		for _, d := range entries {
+			if d.Name == meta.Default {
+				if e.Meta.Tunnel != nil {
+					c.closeTunnel(configPath, e.Name, e.Meta.Tunnel)
+				}
+				return c.activate(configPath, d, meta, "", "")
+			}
+		}
+	}
+	return c.deactivate(configPath, e, meta)
+}
+
+// removeByPattern removes all the entries whose names match the glob pattern, after confirmation
+func (c *Config) removeByPattern(configPath string) error {
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	matched, err := matchEntries(entries, c.Pattern)
+	if err != nil {
+		return err
+	}
+	if err = c.checkProtected(matched); err != nil {
+		return err
+	}
+
+	for _, e := range matched {
+		fmt.Printf("%s -> %s\n", e.Name, kconf.Target(e.Path))
+	}
+	if c.DryRun {
+		fmt.Printf("%d kubeconfigs would be removed\n", len(matched))
+		return nil
+	}
+	if !c.Yes && !confirm(fmt.Sprintf("Remove %d kubeconfigs?", len(matched))) {
+		return fmt.Errorf("aborted")
+	}
+
+	return c.removeEntries(matched, meta)
+}
+
+// checkProtected returns an error if any of the entries is protected from removal, unless forced
+func (c *Config) checkProtected(entries []*kconf.Entry) error {
+	if c.Force {
 		return nil
 	}
 
-	idx, err := strconv.Atoi(args[0])
+	var names []string
+	for _, e := range entries {
+		if e.Meta.Protected {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) > 0 {
+		return kconf.InvalidErrorf("protected kubeconfigs (use -f to remove): %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// removeEntries removes the given entries and saves the metadata
+func (c *Config) removeEntries(entries []*kconf.Entry, meta *kconf.Metadata) error {
+	var err error
+	for _, e := range entries {
+		if err = c.removeEntry(e, meta); err != nil {
+			break
+		}
+	}
+	// keep the metadata of the removed entries in sync even on failure
+	if serr := c.Library.Save(meta); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func (c *Config) clearLibrary(configPath string, args []string) error {
+	entries, meta, err := c.Library.Entries()
 	if err != nil {
-		// filename not index
-		for _, file := range files {
-			if file.Name() == strings.TrimSpace(args[0]) {
-				return result(path.Join(configPath, file.Name()))
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if err = c.checkProtected(entries); err != nil {
+		return err
+	}
+
+	if c.DryRun {
+		for _, e := range entries {
+			fmt.Printf("%s -> %s would be removed\n", e.Name, kconf.Target(e.Path))
+			if c.Purge {
+				fmt.Printf("%s would be deleted\n", kconf.Target(e.Path))
 			}
 		}
+		return nil
 	}
 
-	if idx < 0 || idx > len(files) {
-		return fmt.Errorf("index out of range")
+	question := fmt.Sprintf("Remove all %d kubeconfigs?", len(entries))
+	if c.Purge {
+		question = fmt.Sprintf("Remove all %d kubeconfigs and delete their files?", len(entries))
+	}
+	if !c.Yes && !confirm(question) {
+		return fmt.Errorf("aborted")
 	}
 
-	return result(path.Join(configPath, files[idx-1].Name()))
+	for _, e := range entries {
+		file := kconf.Target(e.Path)
+		if err = c.removeEntry(e, meta); err != nil {
+			break
+		}
+		if !c.Purge {
+			continue
+		}
+		// several entries may point to the same file
+		if err = os.Remove(file); err == nil {
+			c.infof("%s deleted\n", file)
+		} else if os.IsNotExist(err) {
+			err = nil
+		} else {
+			break
+		}
+	}
+	// keep the metadata of the removed entries in sync even on failure
+	if serr := c.Library.Save(meta); err == nil {
+		err = serr
+	}
+	return err
 }
 
-func setKubeconfig(configPath string, args []string) error {
-	return makeKubeconfig(configPath, args, output)
+func (c *Config) protectKubeconfig(configPath string, args []string) error {
+	return c.makeKubeconfig(configPath, args, func(e *kconf.Entry, meta *kconf.Metadata) error {
+		e.Meta.Protected = !c.Delete
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+
+		if c.Delete {
+			c.infof("%s unprotected\n", e.Name)
+		} else {
+			c.infof("%s protected\n", e.Name)
+		}
+		return nil
+	})
 }
 
-func removeKubeconfig(configPath string, args []string) error {
-	return makeKubeconfig(configPath, args, remove)
+func (c *Config) updateKubeconfig(configPath string, args []string) error {
+	if len(args) < 2 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+
+	file, err := filepath.Abs(args[1])
+	if err != nil {
+		return err
+	}
+
+	if !exists(file) {
+		return kconf.NotFoundErrorf("kubeconfig not found: %s", file)
+	}
+
+	// the entry keeps its index, aliases and usage
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if c.DryRun {
+			fmt.Printf("%s -> %s would be updated\n", e.Name, file)
+			return nil
+		}
+		if err := c.Library.Relink(e, file, c.linkOptions()); err != nil {
+			return err
+		}
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+		c.infof("%s -> %s updated\n", e.Name, file)
+		return nil
+	})
 }
 
-// configPath returns the full path to the config directory (creates it if doesn't exists)
-func configPath() (string, error) {
+// configPath returns the full path to the config directory (creates it if doesn't exists), the XDG data directory by default.
+// The config directory of a shared library root is the sub-library of the invoking user.
+func configPath(override string) (string, error) {
 	configPath := strings.TrimSpace(os.Getenv(confPathVar))
-	if len(configPath) == 0 {
+	if override != "" {
+		configPath = override
+		debugf("config path from --library: %s", configPath)
+	} else if len(configPath) == 0 {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
 			return "", err
 		}
-		configPath = homeDir + "/" + defaultConfigDir
+		return xdgLibrary(filepath.ToSlash(homeDir))
+	} else {
+		debugf("config path from %s: %s", confPathVar, configPath)
 	}
 
+	if exists(path.Join(configPath, sharedMarker)) {
+		return userLibrary(configPath)
+	}
 	if exists(configPath) {
 		return configPath, nil
 	}
 
+	debugf("creating config directory %s", configPath)
 	return configPath, os.Mkdir(configPath, confDirFileMode)
 }
 
@@ -222,66 +1582,135 @@ func configPath() (string, error) {
     Helpers
 **************/
 
-// ouput prints set KUBECONFIG variable
-func output(linkPath string) error {
-	fmt.Printf("export %s=%s\n", kubeConfigVar, linkPath)
+// ouput prints the commands setting KUBECONFIG variable in the caller shell, along with the given NAME=value variables
+func (c *Config) output(linkPath string, env ...string) error {
+	fmt.Println(c.exportCommand(kubeConfigVar, linkPath))
+	for _, kv := range env {
+		kv := strings.SplitN(kv, "=", 2)
+		fmt.Println(c.exportCommand(kv[0], kv[1]))
+	}
 	return nil
 }
 
-// remove removes the link for the config directory
-func remove(linkPath string) error {
-	kubeConfigPath, err := os.Readlink(linkPath)
+// removeEntry removes the entry link and its metadata (the caller saves the metadata)
+func (c *Config) removeEntry(e *kconf.Entry, meta *kconf.Metadata) error {
+	kubeConfigPath, err := c.Library.Remove(e, meta)
 	if err != nil {
 		return err
 	}
-
-	if err = os.Remove(linkPath); err != nil {
-		return err
+	if e.Meta.Tunnel != nil {
+		c.closeTunnel(c.Library.Path, e.Name, e.Meta.Tunnel)
 	}
+	removeWorkCopies(c.Library.Path, e.Name)
 
-	fmt.Printf("%s -> %s removed\n", path.Base(linkPath), kubeConfigPath)
+	c.infof("%s -> %s removed\n", e.Name, kubeConfigPath)
 	return nil
 }
 
-// exists returns true of the given path exists
-func exists(path string) bool {
-	_, err := os.Stat(path)
-	return !os.IsNotExist(err)
+// infof prints the informational message unless the quiet mode is on
+func (c *Config) infof(format string, a ...interface{}) {
+	if !c.Quiet {
+		fmt.Printf(format, a...)
+	}
 }
 
-// listSymDir returns a list of symlinks which the given directory contains
-func listSymDir(dir string) ([]fs.FileInfo, error) {
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return nil, err
+// linkOptions returns the options of the links to the added kubeconfigs
+func (c *Config) linkOptions() kconf.LinkOptions {
+	return kconf.LinkOptions{
+		Relative: c.Relative || c.Settings.RelativeLinks,
+		Copy:     c.Copy || c.Settings.Copy,
 	}
+}
 
-	var res []fs.FileInfo
-	for _, file := range files {
-		if !file.IsDir() && (file.Mode()&fs.ModeSymlink == fs.ModeSymlink) {
-			res = append(res, file)
-		}
+// pickEntry asks to select one of the entries by its index or name on the terminal
+func (c *Config) pickEntry(entries []*kconf.Entry) (*kconf.Entry, error) {
+	if !isTerminal(os.Stdin) {
+		return nil, kconf.InvalidErrorf("expected the kubeconfig name")
 	}
-	return res, nil
+	if len(entries) == 0 {
+		return nil, kconf.NotFoundErrorf("no kubeconfigs in the library")
+	}
+	for _, e := range entries {
+		fmt.Fprintf(os.Stderr, "%3s) %s\n", indexLabel(e), e.Name)
+	}
+	fmt.Fprint(os.Stderr, "Select kubeconfig: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, fmt.Errorf("aborted")
+	}
+	return c.findEntry(entries, answer)
+}
+
+// confirm asks the user the given question and returns true if the answer is yes
+// The question goes to stderr not to mix with the output evaluated by the shell.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// isTerminal returns true if the file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// exists returns true of the given path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
 }
 
 func main() {
 	cfg := &Config{}
 	cfg.Flags()
 
-	if !cfg.Validate() {
-		fmt.Println("error validating flags")
-		os.Exit(1)
+	if err := cfg.Validate(); err != nil {
+		cfg.exit("error validating flags:", err)
 	}
 
-	configPath, err := configPath()
+	configPath, err := configPath(cfg.LibraryPath)
 	if err != nil {
-		fmt.Println("error getting config path:", err)
-		os.Exit(1)
+		cfg.exit("error getting config path:", err)
+	}
+	if cfg.LibraryPath != "" {
+		// the kconf commands run by the child processes, the exec plugins for instance, use the same library
+		os.Setenv(confPathVar, cfg.LibraryPath)
+	}
+
+	cfg.Library = kconf.New(configPath)
+	cfg.Library.Logger = debugLog
+
+	if cfg.Settings, err = loadSettings(configPath); err != nil {
+		cfg.exit("error reading config file:", err)
+	}
+	if err = cfg.applyNetworkSettings(); err != nil {
+		cfg.exit("error validating network options:", err)
+	}
+	if !cfg.DryRun {
+		cfg.autoTrash()
+		cfg.refreshWorkCopy(configPath)
 	}
 
 	if err = cfg.Handler()(configPath, cfg.Args()); err != nil {
-		fmt.Println("error handling operation:", err)
-		os.Exit(1)
+		cfg.exit("error handling operation:", err)
+	}
+}
+
+// exit prints the error unless the quiet mode is on and exits with the code matching the error
+func (c *Config) exit(msg string, err error) {
+	var ce *codeError
+	switch {
+	case c.Quiet, errors.As(err, &ce) && ce.silent:
+	case c.Output == outputJSON:
+		printJSONError(os.Stderr, err)
+	default:
+		fmt.Fprintln(os.Stderr, msg, err)
 	}
+	os.Exit(exitCode(err))
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// toastScript shows the Windows toast notification with the title and the message appended to it
+const toastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$x = $t.GetElementsByTagName('text')
+$x.Item(0).AppendChild($t.CreateTextNode(%s)) > $null
+$x.Item(1).AppendChild($t.CreateTextNode(%s)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('kconf').Show([Windows.UI.Notifications.ToastNotification]::new($t))`
+
+// notify sends a desktop notification when the activated entry has any of the tags of the notify settings.
+// The notification is best effort: the missing notifiers are only logged.
+func (c *Config) notify(e *kconf.Entry, context string) {
+	selector := c.Settings.Notify.Tags
+	if len(selector) == 0 || !matchTags(e.Meta.Tags, selector) {
+		return
+	}
+
+	title := fmt.Sprintf("kconf: %s active", e.Name)
+	if context != "" {
+		title = fmt.Sprintf("kconf: %s/%s active", e.Name, context)
+	}
+	message := formatTags(e.Meta.Tags)
+	cmd := notifyCommand(runtime.GOOS, title, message)
+	debugf("notify: %s", strings.Join(cmd.Args, " "))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		debugf("cannot send notification: %v: %s", err, out)
+	}
+}
+
+// notifyCommand returns the command sending the urgent desktop notification of the operating system
+func notifyCommand(goos, title, message string) *exec.Cmd {
+	switch goos {
+	case "darwin":
+		// the arguments keep the title and the message out of the script
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", `display notification (item 2 of argv) with title (item 1 of argv) sound name "Basso"`,
+			"-e", "end run",
+			title, message)
+	case "windows":
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+			fmt.Sprintf(toastScript, powerShellQuote(title), powerShellQuote(message)))
+	}
+	return exec.Command("notify-send", "--urgency=critical", "--app-name=kconf", title, message)
+}
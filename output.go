@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// jsonEntry is the machine readable form of an entry
+type jsonEntry struct {
+	Index     int               `json:"index"`
+	Name      string            `json:"name"`
+	Path      string            `json:"path"`
+	Target    string            `json:"target"`
+	Aliases   []string          `json:"aliases,omitempty"`
+	Context   string            `json:"context,omitempty"`
+	Server    string            `json:"server,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Status    *probeResult      `json:"status,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Protected bool              `json:"protected,omitempty"`
+	Default   bool              `json:"default,omitempty"`
+	AddedAt   time.Time         `json:"added_at"`
+	LastUsed  time.Time         `json:"last_used"`
+	Active    bool              `json:"active"`
+}
+
+// printJSONEntries writes the entries as a JSON array
+func (c *Config) printJSONEntries(w io.Writer, entries []*kconf.Entry, infos map[string]*EntryInfo, clusters map[string]*ClusterData, currName, defaultName string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.entryModels(entries, infos, clusters, currName, defaultName))
+}
+
+// printFormattedEntries writes the entries with the Go template, one per line
+func (c *Config) printFormattedEntries(w io.Writer, tmpl *template.Template, entries []*kconf.Entry, infos map[string]*EntryInfo, clusters map[string]*ClusterData, currName, defaultName string) error {
+	for _, e := range c.entryModels(entries, infos, clusters, currName, defaultName) {
+		if err := tmpl.Execute(w, e); err != nil {
+			return kconf.InvalidErrorf("cannot format %q: %w", e.Name, err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryModels returns the machine readable forms of the entries
+func (c *Config) entryModels(entries []*kconf.Entry, infos map[string]*EntryInfo, clusters map[string]*ClusterData, currName, defaultName string) []jsonEntry {
+	res := make([]jsonEntry, 0, len(entries))
+	for _, e := range entries {
+		info := infos[e.Name]
+		var status *probeResult
+		var version string
+		if data, ok := clusters[e.Name]; ok {
+			if c.Status {
+				status = data.Status
+			}
+			if c.ServerVersion {
+				version = data.Version
+			}
+		}
+		res = append(res, jsonEntry{
+			Index:     e.Meta.Index,
+			Name:      e.Name,
+			Path:      e.Path,
+			Target:    info.Target,
+			Aliases:   e.Meta.Aliases,
+			Context:   info.Context,
+			Server:    info.Server,
+			Namespace: info.Namespace,
+			Error:     info.Error,
+			Status:    status,
+			Version:   version,
+			Tags:      e.Meta.Tags,
+			Protected: e.Meta.Protected,
+			Default:   e.Name == defaultName,
+			AddedAt:   e.AddedAt(),
+			LastUsed:  e.Meta.LastUsed,
+			Active:    e.Name == currName,
+		})
+	}
+	return res
+}
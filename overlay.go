@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// overlayKubeconfig adds the kubeconfig generated from the clusters and contexts of the base entry
+// and the user of the file, or regenerates all the overlay kubeconfigs with --refresh
+func (c *Config) overlayKubeconfig(configPath string, args []string) error {
+	if c.Refresh {
+		if len(args) > 0 {
+			return kconf.InvalidErrorf("--refresh takes no arguments")
+		}
+		return c.refreshOverlays()
+	}
+	if len(args) != 3 {
+		return kconf.InvalidErrorf("expected the base kubeconfig, the user file and the name")
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	base, err := c.findEntry(entries, args[0])
+	if err != nil {
+		return err
+	}
+	userFile, err := filepath.Abs(args[1])
+	if err != nil {
+		return err
+	}
+	overlay := &kconf.Overlay{Base: base.Name, UserFile: userFile, User: c.User}
+	data, err := renderOverlay(base, overlay)
+	if err != nil {
+		return err
+	}
+
+	name := args[2]
+	if c.DryRun {
+		fmt.Printf("%s would be added:\n%s", name, data)
+		return nil
+	}
+	replace, err := c.addData(data, name)
+	if err != nil {
+		return err
+	}
+
+	// the metadata is saved by the addition
+	_, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	meta.Entry(name).Overlay = overlay
+	if err = c.Library.Save(meta); err != nil {
+		return err
+	}
+	if replace {
+		c.infof("%s = %s + %s replaced\n", name, base.Name, args[1])
+	} else {
+		c.infof("%s = %s + %s added\n", name, base.Name, args[1])
+	}
+	return nil
+}
+
+// refreshOverlays regenerates the overlay kubeconfigs whose pieces changed, the previous contents are kept in their history
+func (c *Config) refreshOverlays() error {
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, e := range entries {
+		if e.Meta.Overlay == nil {
+			continue
+		}
+		base, err := kconf.FindName(entries, e.Meta.Overlay.Base)
+		if err == nil && base.Meta.Overlay == nil {
+			err = c.refreshOverlay(e, base)
+		} else if err == nil {
+			err = kconf.InvalidErrorf("base %q is an overlay", base.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot refresh %s: %v\n", e.Name, err)
+			failed = append(failed, e.Name)
+		}
+	}
+
+	if !c.DryRun {
+		if err = c.Library.Save(meta); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d overlay kubeconfigs not refreshed", len(failed))
+	}
+	return nil
+}
+
+// refreshOverlay regenerates the overlay kubeconfig if its content changed, the caller saves the metadata
+func (c *Config) refreshOverlay(e, base *kconf.Entry) error {
+	data, err := renderOverlay(base, e.Meta.Overlay)
+	if err != nil {
+		return err
+	}
+	if current, err := os.ReadFile(e.Path); err == nil && bytes.Equal(current, data) {
+		debugf("%q up to date", e.Name)
+		return nil
+	}
+
+	if c.DryRun {
+		fmt.Printf("%s would be refreshed\n", e.Name)
+		return nil
+	}
+	if err = c.Library.Rewrite(e, data); err != nil {
+		return err
+	}
+	c.infof("%s refreshed\n", e.Name)
+	return nil
+}
+
+// renderOverlay returns the kubeconfig of the base entry with its users replaced by the user of the overlay.
+// All the contexts use the overlay user.
+func renderOverlay(base *kconf.Entry, overlay *kconf.Overlay) ([]byte, error) {
+	kc, err := loadKubeconfig(base.Path)
+	if err != nil {
+		return nil, kconf.EntryError(base.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	user, err := loadUser(overlay.UserFile, overlay.User)
+	if err != nil {
+		return nil, err
+	}
+
+	// the pieces come from different directories
+	absolutePaths(kc, filepath.Dir(kconf.Target(base.Path)))
+	kc.setUser(user)
+	return kc.marshal()
+}
+
+// loadUser returns the named user of the kubeconfig file (the first one if no name), its file paths are absolute
+func loadUser(file, name string) (*NamedUser, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	uc, err := loadKubeconfig(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, kconf.InvalidErrorf("cannot parse user file: %w", err)
+	}
+	absolutePaths(uc, filepath.Dir(file))
+
+	for i := range uc.Users {
+		if name == "" || uc.Users[i].Name == name {
+			return &uc.Users[i], nil
+		}
+	}
+	if name != "" {
+		return nil, kconf.NotFoundErrorf("user not found in %s: %q", file, name)
+	}
+	return nil, kconf.InvalidErrorf("no users in %s", file)
+}
+
+// setUser replaces the users of the kubeconfig with the given one, all the contexts use it
+func (k *Kubeconfig) setUser(user *NamedUser) {
+	k.Users = []NamedUser{*user}
+	for i := range k.Contexts {
+		k.Contexts[i].Context.User = user.Name
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	pagerVar     = "PAGER"
+	defaultPager = "less"
+	// lessVar configures less: without it, the output fitting the screen is printed as is, colors included
+	lessVar          = "LESS"
+	defaultLessFlags = "FRX"
+)
+
+// startPager sends the standard output to $PAGER if it goes to a terminal, returns the function closing the pager.
+// The output is printed directly if the pager is disabled or can't be found.
+func (c *Config) startPager() func() {
+	noop := func() {}
+	if c.NoPager || !isTerminal(os.Stdout) {
+		return noop
+	}
+	pager := os.Getenv(pagerVar)
+	if pager == "" {
+		pager = defaultPager
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 || args[0] == "cat" {
+		return noop
+	}
+	program, err := exec.LookPath(args[0])
+	if err != nil {
+		debugf("no pager: %v", err)
+		return noop
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		debugf("no pager: %v", err)
+		return noop
+	}
+	cmd := exec.Command(program, args[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv(lessVar); !ok {
+		cmd.Env = append(cmd.Env, lessVar+"="+defaultLessFlags)
+	}
+	// the colors are decided on the terminal, not on the pipe
+	if c.Color == colorAuto && c.colorEnabled() {
+		c.Color = colorAlways
+	}
+	if err = cmd.Start(); err != nil {
+		debugf("no pager: %v", err)
+		r.Close()
+		w.Close()
+		return noop
+	}
+	debugf("paging with %s", pager)
+
+	stdout := os.Stdout
+	os.Stdout = w
+	return func() {
+		w.Close()
+		os.Stdout = stdout
+		_ = cmd.Wait()
+		r.Close()
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+	"gopkg.in/yaml.v3"
+)
+
+// patchDirective is the key of the strategic merge patch deleting the named item of a list
+const patchDirective = "$patch"
+
+// patchKubeconfig sets the patch of the entry from the file (- for stdin), prints it without file or deletes it.
+// The patch is applied by set and exec to a working copy of the kubeconfig, the kubeconfig itself is not changed.
+func (c *Config) patchKubeconfig(configPath string, args []string) error {
+	if len(args) != 1 && (c.Delete || len(args) != 2) {
+		return kconf.InvalidErrorf("expected the kubeconfig name and the patch file")
+	}
+
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		switch {
+		case c.Delete:
+			e.Meta.Patch = ""
+		case len(args) == 1:
+			if e.Meta.Patch == "" {
+				return kconf.EntryError(e.Name, kconf.NotFoundErrorf("kubeconfig %q has no patch", e.Name))
+			}
+			fmt.Print(e.Meta.Patch)
+			return nil
+		default:
+			patch, err := readPatch(args[1])
+			if err != nil {
+				return err
+			}
+			// the patch is checked against the current content
+			kc, err := loadKubeconfig(e.Path)
+			if err != nil {
+				return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+			}
+			if _, err = applyPatch(kc, patch); err != nil {
+				return kconf.EntryError(e.Name, err)
+			}
+			e.Meta.Patch = patch
+		}
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+
+		if c.Delete {
+			c.infof("%s patch removed\n", e.Name)
+		} else {
+			c.infof("%s patched with %s\n", e.Name, args[1])
+		}
+		return nil
+	})
+}
+
+// readPatch returns the content of the patch file, stdin for -
+func readPatch(file string) (string, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	return string(data), nil
+}
+
+// applyPatch returns the kubeconfig with the patch applied: a JSON patch if it's a list of operations,
+// a strategic merge patch merging the clusters, contexts and users by name otherwise
+func applyPatch(kc *Kubeconfig, patch string) (*Kubeconfig, error) {
+	var p interface{}
+	if err := yaml.Unmarshal([]byte(patch), &p); err != nil {
+		return nil, kconf.InvalidErrorf("invalid patch: %w", err)
+	}
+
+	data, err := kc.marshal()
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	switch p := p.(type) {
+	case []interface{}:
+		if doc, err = applyJSONPatch(doc, p); err != nil {
+			return nil, kconf.InvalidErrorf("cannot apply JSON patch: %w", err)
+		}
+	case map[string]interface{}:
+		doc = mergePatch(doc, p)
+	default:
+		return nil, kconf.InvalidErrorf("invalid patch: expected a strategic merge patch or a JSON patch")
+	}
+
+	if data, err = yaml.Marshal(doc); err != nil {
+		return nil, err
+	}
+	res, err := parseKubeconfig(data)
+	if err != nil {
+		return nil, kconf.InvalidErrorf("invalid patched kubeconfig: %w", err)
+	}
+	return res, nil
+}
+
+// mergePatch merges the patch into the value: the maps recursively, the lists of named items by name.
+// The null values and the items with $patch: delete are removed.
+func mergePatch(value, patch interface{}) interface{} {
+	switch p := patch.(type) {
+	case map[string]interface{}:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{}
+		}
+		for k, v := range p {
+			if v == nil {
+				delete(m, k)
+				continue
+			}
+			m[k] = mergePatch(m[k], v)
+		}
+		return m
+	case []interface{}:
+		l, ok := value.([]interface{})
+		if !ok || !namedItems(l) || !namedItems(p) {
+			return p
+		}
+		for _, item := range p {
+			pi := item.(map[string]interface{})
+			i := indexOfName(l, pi["name"])
+			if pi[patchDirective] == "delete" {
+				if i >= 0 {
+					l = append(l[:i], l[i+1:]...)
+				}
+				continue
+			}
+			if i < 0 {
+				l = append(l, pi)
+			} else {
+				l[i] = mergePatch(l[i], pi)
+			}
+		}
+		return l
+	}
+	return patch
+}
+
+// namedItems returns true if all the items of the list are maps with a name
+func namedItems(l []interface{}) bool {
+	for _, item := range l {
+		m, ok := item.(map[string]interface{})
+		if !ok || m["name"] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOfName(l []interface{}, name interface{}) int {
+	for i, item := range l {
+		if item.(map[string]interface{})["name"] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyJSONPatch applies the add, remove, replace and test operations of the RFC 6902 patch
+func applyJSONPatch(doc interface{}, ops []interface{}) (interface{}, error) {
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %d is not an object", i)
+		}
+		name, _ := op["op"].(string)
+		pointer, _ := op["path"].(string)
+		if pointer != "" && !strings.HasPrefix(pointer, "/") {
+			return nil, fmt.Errorf("invalid path %q", pointer)
+		}
+		var tokens []string
+		if pointer != "" {
+			for _, t := range strings.Split(pointer[1:], "/") {
+				tokens = append(tokens, strings.NewReplacer("~1", "/", "~0", "~").Replace(t))
+			}
+		}
+
+		var err error
+		switch name {
+		case "add", "replace", "remove":
+			doc, err = patchPointer(doc, tokens, name, op["value"])
+		case "test":
+			var v interface{}
+			if v, err = lookupPointer(doc, tokens); err == nil && !reflect.DeepEqual(v, op["value"]) {
+				err = fmt.Errorf("test of %s failed", pointer)
+			}
+		default:
+			err = fmt.Errorf("unsupported operation %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// patchPointer applies the operation at the path given by the tokens of the JSON pointer and returns the new value
+func patchPointer(value interface{}, tokens []string, op string, v interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		if op == "remove" {
+			return nil, fmt.Errorf("cannot remove the whole document")
+		}
+		return v, nil
+	}
+	last := len(tokens) == 1
+	switch node := value.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok && (op != "add" || !last) {
+			return nil, fmt.Errorf("no key %q", tokens[0])
+		}
+		if last && op == "remove" {
+			delete(node, tokens[0])
+			return node, nil
+		}
+		res, err := patchPointer(child, tokens[1:], op, v)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = res
+		return node, nil
+	case []interface{}:
+		if last && op == "add" && tokens[0] == "-" {
+			return append(node, v), nil
+		}
+		i, err := strconv.Atoi(tokens[0])
+		if err != nil || i < 0 || i > len(node) || (i == len(node) && (op != "add" || !last)) {
+			return nil, fmt.Errorf("invalid index %q", tokens[0])
+		}
+		switch {
+		case last && op == "add":
+			node = append(node[:i], append([]interface{}{v}, node[i:]...)...)
+			return node, nil
+		case last && op == "remove":
+			return append(node[:i], node[i+1:]...), nil
+		}
+		res, err := patchPointer(node[i], tokens[1:], op, v)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = res
+		return node, nil
+	}
+	return nil, fmt.Errorf("no key %q", tokens[0])
+}
+
+// lookupPointer returns the value at the path given by the tokens of the JSON pointer
+func lookupPointer(value interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("no key %q", t)
+			}
+			value = v
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("invalid index %q", t)
+			}
+			value = node[i]
+		default:
+			return nil, fmt.Errorf("no key %q", t)
+		}
+	}
+	return value, nil
+}
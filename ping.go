@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// targetEntries returns the entries selected by the arguments, all of them with --all or the current kubeconfig.
+// The current kubeconfig is an entry without metadata if it's not in the library.
+func (c *Config) targetEntries(entries []*kconf.Entry, args []string) ([]*kconf.Entry, error) {
+	switch {
+	case c.All:
+		if len(args) > 0 {
+			return nil, kconf.InvalidErrorf("--all cannot be used with kubeconfig names")
+		}
+		return entries, nil
+	case len(args) > 0:
+		return c.selectEntries(entries, args)
+	}
+
+	curr := envKubeconfig()
+	if curr == "" {
+		return nil, kconf.InvalidErrorf("no kubeconfig set, expected a name or --all")
+	}
+	for _, e := range entries {
+		if e.Path == curr {
+			return []*kconf.Entry{e}, nil
+		}
+	}
+	return []*kconf.Entry{{Name: curr, Path: curr, Meta: &kconf.EntryMeta{}}}, nil
+}
+
+// pingResult is the probe result of an entry
+type pingResult struct {
+	Name string `json:"name"`
+	*probeResult
+}
+
+// pingKubeconfigs probes the API servers of the given entries (the current kubeconfig by default, all the entries with --all)
+func (c *Config) pingKubeconfigs(configPath string, args []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	// the standard kubeconfig listed as default
+	if implicit := kubeDefaultEntry(entries); implicit != nil {
+		entries = append(entries, implicit)
+	}
+
+	targets, err := c.targetEntries(entries, args)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	results := make([]pingResult, 0, len(targets))
+	forEachConcurrently(targets, c.Net.Workers, func(e *kconf.Entry) {
+		res := probe(e.Path, c.Net)
+		mu.Lock()
+		results = append(results, pingResult{Name: e.Name, probeResult: res})
+		mu.Unlock()
+	})
+	order := map[string]int{}
+	for i, e := range targets {
+		order[e.Name] = i
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return order[results[i].Name] < order[results[j].Name]
+	})
+
+	var down []string
+	for _, res := range results {
+		if isDown(res.Status) {
+			down = append(down, res.Name)
+		}
+	}
+
+	if c.Output == outputJSON {
+		if err = json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, res := range results {
+			httpStatus := "-"
+			if res.HTTPStatus != 0 {
+				httpStatus = fmt.Sprint(res.HTTPStatus)
+			}
+			latency := "-"
+			if res.Latency != 0 {
+				latency = res.Latency.Round(time.Millisecond).String()
+			}
+			line := fmt.Sprintf("%s\t%s\t%s\t%s", res.Name, res.Status, httpStatus, latency)
+			if res.Error != "" {
+				line += "\t" + res.Error
+			}
+			fmt.Fprintln(w, line)
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(down) > 0 {
+		return networkErrorf("%d of %d clusters down: %s", len(down), len(results), strings.Join(down, ", "))
+	}
+	return nil
+}
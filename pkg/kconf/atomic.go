@@ -0,0 +1,67 @@
+package kconf
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// tempName returns a unique hidden name in the directory of the given file.
+// Hidden names are not listed as entries.
+func tempName(file string) string {
+	return path.Join(path.Dir(file), fmt.Sprintf(".%s.%d.%d.tmp", path.Base(file), os.Getpid(), time.Now().UnixNano()))
+}
+
+// ReplaceSymlink atomically replaces the given symlink with a new one pointing to the target:
+// the readers see either the old or the new link, never a missing one
+func ReplaceSymlink(target, linkPath string) error {
+	tmp := tempName(linkPath)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, linkPath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(path.Dir(linkPath))
+}
+
+// WriteFileAtomic writes the file through a temporary file renamed over it:
+// the readers see either the old or the new content, never a partially written one
+func WriteFileAtomic(file string, data []byte, mode os.FileMode) error {
+	tmp := tempName(file)
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(path.Dir(file))
+}
+
+// syncDir flushes the directory entries to make the renames durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// not supported by all the platforms and filesystems
+	_ = d.Sync()
+	return nil
+}
@@ -0,0 +1,272 @@
+package kconf
+
+import (
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the maximum length of the entry name
+const MaxNameLength = 128
+
+// Entry is a kubeconfig registered in the library
+type Entry struct {
+	// Name is the path of the link relative to the library directory
+	Name string
+	// Path is the path of the link
+	Path string
+	Meta *EntryMeta
+
+	dirEntry fs.DirEntry
+}
+
+// AddedAt returns the time the entry was added to the library
+func (e *Entry) AddedAt() time.Time {
+	if !e.Meta.AddedAt.IsZero() || e.dirEntry == nil {
+		return e.Meta.AddedAt
+	}
+
+	// added before the metadata was tracked: the link is as old as the entry
+	info, err := e.dirEntry.Info()
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// matchers are the name matching rules from the strictest to the loosest
+var matchers = []func(name, arg string) bool{
+	func(name, arg string) bool { return name == arg },
+	strings.HasPrefix,
+	strings.Contains,
+	isSubsequence,
+}
+
+// Find returns the entry given by its index or name.
+// A number is the index of an entry unless an entry has exactly this name.
+func Find(entries []*Entry, arg string, ignoreCase bool) (*Entry, error) {
+	arg = strings.TrimSpace(arg)
+
+	if _, err := strconv.Atoi(arg); err != nil || hasName(entries, arg) {
+		// filename not index
+		e, err := Match(entries, arg, ignoreCase)
+		if err != nil {
+			return nil, EntryError(arg, err)
+		}
+		return e, nil
+	}
+	return FindIndex(entries, arg)
+}
+
+// FindIndex returns the entry given by its index, never by its name
+func FindIndex(entries []*Entry, arg string) (*Entry, error) {
+	arg = strings.TrimSpace(arg)
+	idx, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, EntryError(arg, InvalidErrorf("invalid index %q", arg))
+	}
+	if idx < 1 {
+		return nil, EntryError(arg, InvalidErrorf("invalid index %d, the indexes start at 1", idx))
+	}
+	for _, e := range entries {
+		if e.Meta.Index == idx {
+			return e, nil
+		}
+	}
+	return nil, EntryError(arg, NotFoundErrorf("no kubeconfig with index %d", idx))
+}
+
+// FindName returns the entry of exactly the given name, for the names recorded by kconf itself:
+// the entries matched only partially or by their indexes are not the recorded ones
+func FindName(entries []*Entry, name string) (*Entry, error) {
+	for _, e := range entries {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+	return nil, EntryError(name, NotFoundErrorf("kubeconfig not found: %q", name))
+}
+
+// hasName returns true if an entry has exactly the name
+func hasName(entries []*Entry, name string) bool {
+	for _, e := range entries {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the entries given by the comma separated list of indexes, index ranges and names.
+// The ranges like 1-3 select the existing entries with the indexes between the bounds included.
+// The entries selected several times are returned once, in the order of the selection.
+func Select(entries []*Entry, selection string, ignoreCase bool) ([]*Entry, error) {
+	// the names may contain commas
+	for _, e := range entries {
+		if e.Name == selection {
+			return []*Entry{e}, nil
+		}
+	}
+
+	var res []*Entry
+	seen := map[string]bool{}
+	add := func(e *Entry) {
+		if !seen[e.Name] {
+			seen[e.Name] = true
+			res = append(res, e)
+		}
+	}
+
+	for _, part := range strings.Split(selection, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, InvalidErrorf("empty item in selection %q", selection)
+		}
+
+		from, to, isRange, err := parseRange(part)
+		if err != nil {
+			return nil, EntryError(part, err)
+		}
+		if !isRange {
+			e, err := Find(entries, part, ignoreCase)
+			if err != nil {
+				return nil, err
+			}
+			add(e)
+			continue
+		}
+
+		found := false
+		for _, e := range entries {
+			if e.Meta.Index >= from && e.Meta.Index <= to {
+				add(e)
+				found = true
+			}
+		}
+		if !found {
+			return nil, EntryError(part, NotFoundErrorf("no kubeconfig with index in %d-%d", from, to))
+		}
+	}
+	return res, nil
+}
+
+// parseRange parses the from-to index range, the other strings are not ranges
+func parseRange(s string) (int, int, bool, error) {
+	bounds := strings.SplitN(s, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, false, nil
+	}
+	from, ferr := strconv.Atoi(bounds[0])
+	to, terr := strconv.Atoi(bounds[1])
+	if ferr != nil || terr != nil {
+		// a name with a dash
+		return 0, 0, false, nil
+	}
+
+	switch {
+	case from < 1:
+		return 0, 0, false, InvalidErrorf("invalid range %q, the indexes start at 1", s)
+	case from > to:
+		return 0, 0, false, InvalidErrorf("invalid range %q, the start is after the end", s)
+	}
+	return from, to, true, nil
+}
+
+// Match returns the entry matching the given name.
+// The first rule which matches exactly one entry wins, several matches of the same rule are ambiguous.
+// The exact name or alias always wins, the other rules ignore the case if requested.
+func Match(entries []*Entry, arg string, ignoreCase bool) (*Entry, error) {
+	for _, e := range entries {
+		if e.Name == arg {
+			return e, nil
+		}
+	}
+	for _, e := range entries {
+		for _, alias := range e.Meta.Aliases {
+			if alias == arg {
+				return e, nil
+			}
+		}
+	}
+
+	normalize := func(s string) string { return s }
+	if ignoreCase {
+		normalize = strings.ToLower
+	}
+
+	for _, match := range matchers {
+		var found []*Entry
+		for _, e := range entries {
+			if match(normalize(e.Name), normalize(arg)) {
+				found = append(found, e)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+
+		names := make([]string, 0, len(found))
+		for _, e := range found {
+			names = append(names, e.Name)
+		}
+		return nil, InvalidErrorf("ambiguous kubeconfig name %q, candidates: %s", arg, strings.Join(names, ", "))
+	}
+	return nil, NotFoundErrorf("kubeconfig not found: %q", arg)
+}
+
+// isSubsequence returns true if all the characters of sub appear in s in the same order
+func isSubsequence(s, sub string) bool {
+	rs := []rune(s)
+	i := 0
+	for _, r := range sub {
+		for i < len(rs) && rs[i] != r {
+			i++
+		}
+		if i == len(rs) {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// ValidateName checks that the given name can be used as an entry name.
+// Slashes separate the subdirectories of hierarchical names, each part is validated separately.
+func ValidateName(name string) error {
+	switch {
+	case len(name) == 0:
+		return InvalidErrorf("empty kubeconfig name")
+	case len(name) > MaxNameLength:
+		return InvalidErrorf("kubeconfig name longer than %d characters: %q", MaxNameLength, name)
+	case strings.Contains(name, "\\"):
+		return InvalidErrorf("kubeconfig name cannot contain backslashes: %q", name)
+	case !utf8.ValidString(name):
+		return InvalidErrorf("kubeconfig name is not valid UTF-8: %q", name)
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		switch {
+		case len(part) == 0:
+			return InvalidErrorf("kubeconfig name cannot contain empty parts: %q", name)
+		case strings.HasPrefix(part, "-"):
+			return InvalidErrorf("kubeconfig name cannot start with a dash: %q", name)
+		case strings.HasPrefix(part, "."):
+			// reserved for the library files
+			return InvalidErrorf("kubeconfig name cannot start with a dot: %q", name)
+		}
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return InvalidErrorf("kubeconfig name cannot contain control characters: %q", name)
+		}
+	}
+	return nil
+}
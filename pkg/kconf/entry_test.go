@@ -0,0 +1,113 @@
+package kconf
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testEntries() []*Entry {
+	var entries []*Entry
+	for _, e := range []struct {
+		name  string
+		index int
+	}{
+		{"a", 1},
+		{"b", 2},
+		{"c", 3},
+		{"dev-1", 5},
+		{"x,y", 6},
+	} {
+		entries = append(entries, &Entry{Name: e.name, Meta: &EntryMeta{Index: e.index}})
+	}
+	return entries
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection string
+		want      []string
+		wantErr   error
+	}{
+		{name: "single index", selection: "2", want: []string{"b"}},
+		{name: "single name", selection: "c", want: []string{"c"}},
+		{name: "range", selection: "1-3", want: []string{"a", "b", "c"}},
+		{name: "range with a gap", selection: "3-5", want: []string{"c", "dev-1"}},
+		{name: "single index range", selection: "2-2", want: []string{"b"}},
+		{name: "list", selection: "3,1", want: []string{"c", "a"}},
+		{name: "list with spaces", selection: " 3 , b ", want: []string{"c", "b"}},
+		{name: "list of ranges and names", selection: "1-2,dev-1", want: []string{"a", "b", "dev-1"}},
+		{name: "duplicates", selection: "2,1-3,b", want: []string{"b", "a", "c"}},
+		{name: "name with a dash", selection: "dev-1", want: []string{"dev-1"}},
+		{name: "name with a comma", selection: "x,y", want: []string{"x,y"}},
+		{name: "index 0", selection: "0", wantErr: ErrInvalid},
+		{name: "range from 0", selection: "0-2", wantErr: ErrInvalid},
+		{name: "reversed range", selection: "3-1", wantErr: ErrInvalid},
+		{name: "out of range", selection: "7-9", wantErr: ErrNotFound},
+		{name: "missing index", selection: "4", wantErr: ErrNotFound},
+		{name: "missing name", selection: "a,missing", wantErr: ErrNotFound},
+		{name: "empty item", selection: "1,,2", wantErr: ErrInvalid},
+		{name: "trailing comma", selection: "1,", wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Select(testEntries(), tt.selection, false)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Select(%q) error = %v, want %v", tt.selection, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select(%q) unexpected error: %v", tt.selection, err)
+			}
+			var names []string
+			for _, e := range got {
+				names = append(names, e.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Select(%q) = %v, want %v", tt.selection, names, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s       string
+		from    int
+		to      int
+		isRange bool
+		wantErr bool
+	}{
+		{s: "1-3", from: 1, to: 3, isRange: true},
+		{s: "2-2", from: 2, to: 2, isRange: true},
+		{s: "3", isRange: false},
+		{s: "dev-1", isRange: false},
+		{s: "1-dev", isRange: false},
+		{s: "a-b-c", isRange: false},
+		{s: "1-2-3", isRange: false},
+		{s: "0-2", wantErr: true},
+		{s: "3-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			from, to, isRange, err := parseRange(tt.s)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("parseRange(%q) error = %v, want an invalid error", tt.s, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRange(%q) unexpected error: %v", tt.s, err)
+			}
+			if from != tt.from || to != tt.to || isRange != tt.isRange {
+				t.Errorf("parseRange(%q) = %d, %d, %t, want %d, %d, %t", tt.s, from, to, isRange, tt.from, tt.to, tt.isRange)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package kconf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of the errors returned by the library, match them with errors.Is
+var (
+	// ErrNotFound is the kind of the errors about missing kubeconfigs or entries
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is the kind of the errors about invalid arguments or names
+	ErrInvalid = errors.New("invalid")
+)
+
+// Error is an error of a given kind, optionally about an entry
+type Error struct {
+	// Kind is ErrNotFound, ErrInvalid or nil
+	Kind error
+	Err  error
+	// Entry is the name of the entry the error is about
+	Entry string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the target kind
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// NotFoundErrorf returns an error about a missing kubeconfig or entry
+func NotFoundErrorf(format string, a ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Err: fmt.Errorf(format, a...)}
+}
+
+// InvalidErrorf returns an error about invalid arguments or names
+func InvalidErrorf(format string, a ...interface{}) error {
+	return &Error{Kind: ErrInvalid, Err: fmt.Errorf(format, a...)}
+}
+
+// EntryError attaches the name of the entry the error is about
+func EntryError(name string, err error) error {
+	var kind error
+	switch {
+	case errors.Is(err, ErrNotFound):
+		kind = ErrNotFound
+	case errors.Is(err, ErrInvalid):
+		kind = ErrInvalid
+	}
+	return &Error{Kind: kind, Err: err, Entry: name}
+}
@@ -0,0 +1,78 @@
+package kconf
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GC removes the stored contents no longer referenced by any entry or snapshot,
+// and the temporary files older than the given age (left by interrupted writes).
+// Returns the removed files, only lists them if dryRun is set.
+func (l *Library) GC(olderThan time.Duration, dryRun bool) ([]string, error) {
+	entries, meta, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := map[string]bool{}
+	for _, e := range entries {
+		referenced[path.Base(Target(e.Path))] = true
+	}
+	for _, e := range meta.Entries {
+		for _, s := range e.History {
+			referenced[s.Checksum] = true
+		}
+	}
+	// the trashed entries can be restored
+	for _, t := range meta.Trash {
+		referenced[path.Base(t.Link)] = true
+		for _, s := range t.Meta.History {
+			referenced[s.Checksum] = true
+		}
+	}
+
+	var garbage []string
+	blobs, err := os.ReadDir(path.Join(l.Path, blobDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, b := range blobs {
+		if !b.IsDir() && !referenced[b.Name()] && !isTemp(b.Name()) {
+			garbage = append(garbage, path.Join(l.Path, blobDir, b.Name()))
+		}
+	}
+
+	deadline := time.Now().Add(-olderThan)
+	err = filepath.WalkDir(l.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isTemp(d.Name()) {
+			return err
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(deadline) {
+			garbage = append(garbage, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return garbage, nil
+	}
+	for i, file := range garbage {
+		l.debugf("removing %s", file)
+		if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return garbage[:i], err
+		}
+	}
+	return garbage, nil
+}
+
+// isTemp returns true if the name is the name of a temporary file (see tempName)
+func isTemp(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp")
+}
@@ -0,0 +1,123 @@
+package kconf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"time"
+)
+
+// shortChecksumLength is the length of the checksums shown to identify the snapshots
+const shortChecksumLength = 12
+
+// Snapshot is a saved content of the kubeconfig of an entry
+type Snapshot struct {
+	// Version is the sequence number of the snapshot, starting at 1
+	Version int `json:"version"`
+	// Time is when the snapshot was taken
+	Time time.Time `json:"time"`
+	// Checksum is the SHA-256 of the content, the content is stored under it
+	Checksum string `json:"checksum"`
+}
+
+// History returns the snapshots of the entry ordered by their versions
+func (l *Library) History(e *Entry) []*Snapshot {
+	return e.Meta.History
+}
+
+// SnapshotPath returns the file of the snapshot content, the checksum must name a stored content
+func (l *Library) SnapshotPath(s *Snapshot) (string, error) {
+	if !IsChecksum(s.Checksum) {
+		return "", InvalidErrorf("invalid checksum %q of version %d", s.Checksum, s.Version)
+	}
+	return l.blobPath(s.Checksum), nil
+}
+
+// ShortChecksum returns the beginning of the checksum shown to identify the snapshot
+func (s *Snapshot) ShortChecksum() string {
+	if len(s.Checksum) > shortChecksumLength {
+		return s.Checksum[:shortChecksumLength]
+	}
+	return s.Checksum
+}
+
+// StoreSnapshot stores the content of a snapshot taken elsewhere (e.g. in an imported library), returns its checksum
+func (l *Library) StoreSnapshot(data []byte) (string, error) {
+	if _, err := l.storeData(data); err != nil {
+		return "", err
+	}
+	return checksum(data), nil
+}
+
+// Snapshot saves the current content of the entry kubeconfig unless it's the same as the latest snapshot.
+// The caller saves the metadata.
+func (l *Library) Snapshot(e *Entry) (*Snapshot, error) {
+	data, err := os.ReadFile(e.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := checksum(data)
+	version := 1
+	if n := len(e.Meta.History); n > 0 {
+		latest := e.Meta.History[n-1]
+		if latest.Checksum == sum {
+			return latest, nil
+		}
+		version = latest.Version + 1
+	}
+
+	if _, err = l.storeData(data); err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{Version: version, Time: time.Now().UTC().Truncate(time.Second), Checksum: sum}
+	e.Meta.History = append(e.Meta.History, snap)
+	l.debugf("snapshot %d of %q", version, e.Name)
+	return snap, nil
+}
+
+// Rollback points the entry to the snapshot of the given version and records its content, the caller saves the metadata.
+// The current content is snapshot first.
+func (l *Library) Rollback(e *Entry, version int) (*Snapshot, error) {
+	var snap *Snapshot
+	for _, s := range e.Meta.History {
+		if s.Version == version {
+			snap = s
+		}
+	}
+	if snap == nil {
+		return nil, EntryError(e.Name, NotFoundErrorf("no version %d of %q", version, e.Name))
+	}
+
+	file, err := l.SnapshotPath(snap)
+	if err != nil {
+		return nil, EntryError(e.Name, err)
+	}
+	if !exists(file) {
+		return nil, EntryError(e.Name, NotFoundErrorf("content of version %d of %q not stored", version, e.Name))
+	}
+	if current, err := os.ReadFile(e.Path); err == nil && checksum(current) == snap.Checksum {
+		return snap, nil
+	}
+	l.snapshot(e)
+
+	l.debugf("replace symlink %s -> %s", e.Path, file)
+	if err = ReplaceSymlink(file, e.Path); err != nil {
+		return nil, err
+	}
+	e.Meta.Checksum = snap.Checksum
+	return snap, nil
+}
+
+// snapshot saves the content of the entry before it's replaced, the failures don't prevent the replacement
+func (l *Library) snapshot(e *Entry) {
+	if _, err := l.Snapshot(e); err != nil {
+		l.debugf("cannot snapshot %q: %v", e.Name, err)
+	}
+}
+
+// checksum returns the hex encoded SHA-256 of the data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,374 @@
+// Package kconf manages a library of kubeconfigs: a directory of symlinks to the kubeconfig files
+// with the metadata of the entries (indexes, aliases, tags, usage).
+// Hidden files and directories of the library are not entries, they hold the library state.
+package kconf
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	lockFile    = ".lock"
+	dirFileMode = 0755
+)
+
+// Library is a directory of kubeconfig symlinks
+type Library struct {
+	// Path is the library directory
+	Path string
+	// Store persists the metadata of the entries
+	Store Store
+	// Logger gets the debug messages (discarded if nil)
+	Logger *log.Logger
+}
+
+// New returns the library of the given directory with the metadata stored in its MetadataFile
+func New(dir string) *Library {
+	return &Library{
+		Path:  dir,
+		Store: &FileStore{File: path.Join(dir, MetadataFile)},
+	}
+}
+
+// LinkOptions configure the links of the entries
+type LinkOptions struct {
+	// Relative stores the link relative to its directory
+	Relative bool
+	// Copy stores the content of the kubeconfig in the library and links the entry to it:
+	// the entry doesn't change with the original file
+	Copy bool
+}
+
+// AddOptions configure the addition of the kubeconfigs
+type AddOptions struct {
+	LinkOptions
+	// Force replaces the existing entry
+	Force bool
+	// DryRun only checks the kubeconfig can be added
+	DryRun bool
+}
+
+// Entries returns the library entries ordered by their index with the library metadata.
+// Entries without index get the lowest free one, metadata of vanished entries is dropped.
+func (l *Library) Entries() ([]*Entry, *Metadata, error) {
+	return l.entries(true)
+}
+
+// entries returns the library entries and metadata, the changed metadata is saved only if requested
+func (l *Library) entries(save bool) ([]*Entry, *Metadata, error) {
+	files, err := l.listSymDir()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta, err := l.Store.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	l.debugf("read metadata of %d entries", len(meta.Entries))
+
+	changed := false
+	present := map[string]bool{}
+	for _, file := range files {
+		present[file.Name] = true
+	}
+	for name := range meta.Entries {
+		if !present[name] {
+			l.debugf("dropping metadata of vanished entry %q", name)
+			delete(meta.Entries, name)
+			changed = true
+		}
+	}
+	if meta.Default != "" && !present[meta.Default] {
+		l.debugf("dropping vanished default entry %q", meta.Default)
+		meta.Default = ""
+		changed = true
+	}
+
+	entries := make([]*Entry, 0, len(files))
+	for _, file := range files {
+		e := &Entry{
+			Name:     file.Name,
+			Path:     path.Join(l.Path, file.Name),
+			dirEntry: file.Entry,
+			Meta:     meta.Entry(file.Name),
+		}
+		if e.Meta.Index == 0 {
+			e.Meta.Index = meta.FreeIndex()
+			l.debugf("assigned index %d to %q", e.Meta.Index, e.Name)
+			changed = true
+		}
+		entries = append(entries, e)
+	}
+
+	if changed && save {
+		if err = l.Save(meta); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Meta.Index < entries[j].Meta.Index
+	})
+	return entries, meta, nil
+}
+
+// Save stores the library metadata
+func (l *Library) Save(meta *Metadata) error {
+	l.debugf("write metadata of %d entries", len(meta.Entries))
+	return l.Store.Save(meta)
+}
+
+// Add registers the kubeconfig file under the given name, returns true if an existing entry was replaced.
+// The replaced entry keeps its index and aliases.
+func (l *Library) Add(file, name string, opts AddOptions) (bool, error) {
+	if err := ValidateName(name); err != nil {
+		return false, EntryError(name, err)
+	}
+	linkPath := path.Join(l.Path, name)
+
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return false, err
+	}
+	if !exists(file) {
+		return false, NotFoundErrorf("kubeconfig not found: %s", file)
+	}
+
+	replace := false
+	if info, err := os.Lstat(linkPath); err == nil {
+		if !opts.Force {
+			return false, EntryError(name, InvalidErrorf("kubeconfig already exists: %q", name))
+		}
+		if info.Mode()&fs.ModeSymlink != fs.ModeSymlink {
+			return false, InvalidErrorf("cannot replace %q: not a kubeconfig", name)
+		}
+		replace = true
+	}
+
+	// a dry run doesn't store the indexes assigned on the way
+	_, meta, err := l.entries(!opts.DryRun)
+	if err != nil {
+		return false, err
+	}
+	if owner := meta.AliasOwner(name); owner != "" {
+		return false, InvalidErrorf("name already used as alias of %q", owner)
+	}
+	if opts.DryRun {
+		return replace, nil
+	}
+
+	e := meta.Entry(name)
+	if file, err = l.linkFile(file, opts.LinkOptions); err != nil {
+		return false, err
+	}
+	if replace {
+		l.snapshot(&Entry{Name: name, Path: linkPath, Meta: e})
+		l.debugf("replace symlink %s -> %s", linkPath, file)
+		err = ReplaceSymlink(linkTarget(file, linkPath, opts.Relative), linkPath)
+	} else if err = os.MkdirAll(path.Dir(linkPath), dirFileMode); err == nil {
+		l.debugf("symlink %s -> %s", linkPath, file)
+		err = os.Symlink(linkTarget(file, linkPath, opts.Relative), linkPath)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if e.Index == 0 {
+		e.Index = meta.FreeIndex()
+	}
+	e.AddedAt = time.Now()
+	l.record(linkPath, e)
+	return replace, l.Save(meta)
+}
+
+// Relink points the entry to another kubeconfig file and records its content, the caller saves the metadata.
+// The previous content is kept in the entry history.
+func (l *Library) Relink(e *Entry, file string, opts LinkOptions) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if !exists(file) {
+		return NotFoundErrorf("kubeconfig not found: %s", file)
+	}
+	if file, err = l.linkFile(file, opts); err != nil {
+		return err
+	}
+
+	l.snapshot(e)
+	l.debugf("replace symlink %s -> %s", e.Path, file)
+	if err = ReplaceSymlink(linkTarget(file, e.Path, opts.Relative), e.Path); err != nil {
+		return err
+	}
+	l.record(e.Path, e.Meta)
+	return nil
+}
+
+// Rewrite replaces the content of the entry kubeconfig, the previous content is kept in the history.
+// The stored copies are never modified: an entry in the copy mode is linked to the copy of the new content.
+// The caller saves the metadata.
+func (l *Library) Rewrite(e *Entry, data []byte) error {
+	file := Target(e.Path)
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	l.snapshot(e)
+
+	if l.isBlob(file) {
+		blob, err := l.storeData(data)
+		if err != nil {
+			return err
+		}
+		if blob, err = filepath.Abs(blob); err != nil {
+			return err
+		}
+		l.debugf("replace symlink %s -> %s", e.Path, blob)
+		if err = ReplaceSymlink(blob, e.Path); err != nil {
+			return err
+		}
+	} else {
+		l.debugf("write %s", file)
+		if err = WriteFileAtomic(file, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	e.Meta.Checksum = checksum(data)
+	return nil
+}
+
+// linkFile returns the file the entry links to: the stored copy of the kubeconfig in the copy mode
+func (l *Library) linkFile(file string, opts LinkOptions) (string, error) {
+	if !opts.Copy {
+		return file, nil
+	}
+	blob, err := l.storeBlob(file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(blob)
+}
+
+// Remove removes the entry link and its metadata, returns the path the link pointed to.
+// The caller saves the metadata.
+func (l *Library) Remove(e *Entry, meta *Metadata) (string, error) {
+	if _, err := os.Readlink(e.Path); err != nil {
+		return "", err
+	}
+	file := Target(e.Path)
+
+	l.debugf("remove symlink %s -> %s", e.Path, file)
+	if err := os.Remove(e.Path); err != nil {
+		return "", err
+	}
+	l.removeEmptyDirs(path.Dir(e.Path))
+	delete(meta.Entries, e.Name)
+	return file, nil
+}
+
+// Target returns the absolute path of the file the link eventually points to, following relative links and chains.
+// Broken links resolve to their first hop, unreadable ones to the link itself.
+func Target(linkPath string) string {
+	if t, err := filepath.EvalSymlinks(linkPath); err == nil {
+		if abs, err := filepath.Abs(t); err == nil {
+			return abs
+		}
+		return t
+	}
+
+	t, err := os.Readlink(linkPath)
+	if err != nil {
+		return linkPath
+	}
+	if filepath.IsAbs(t) {
+		return t
+	}
+	return filepath.Join(filepath.Dir(linkPath), t)
+}
+
+// linkTarget returns the target to store in the link pointing to the file: relative to the link directory if requested
+func linkTarget(file, linkPath string, relative bool) string {
+	if !relative {
+		return file
+	}
+	rel, err := filepath.Rel(filepath.Dir(linkPath), file)
+	if err != nil {
+		return file
+	}
+	return rel
+}
+
+// removeEmptyDirs removes the given directory and its parents up to the library directory while they are empty
+func (l *Library) removeEmptyDirs(dir string) {
+	for dir != l.Path && strings.HasPrefix(dir, l.Path+"/") {
+		// fails on non empty directories
+		if os.Remove(dir) != nil {
+			return
+		}
+		l.debugf("removed empty directory %s", dir)
+		dir = path.Dir(dir)
+	}
+}
+
+// symFile is a symlink found in a directory tree
+type symFile struct {
+	// Name is the path relative to the tree root
+	Name string
+	// Entry is the directory entry, lstat is done only when its info is requested
+	Entry fs.DirEntry
+}
+
+// listSymDir returns a list of symlinks which the library directory and its subdirectories contain.
+// The symlinks are recognized by the directory entry type, no file is stat'ed.
+// Hidden files and subdirectories are skipped.
+func (l *Library) listSymDir() ([]symFile, error) {
+	var res []symFile
+	err := filepath.WalkDir(l.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != l.Path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != fs.ModeSymlink || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.Path, p)
+		if err != nil {
+			return err
+		}
+		res = append(res, symFile{Name: filepath.ToSlash(rel), Entry: d})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.debugf("found %d symlinks in %s", len(res), l.Path)
+	return res, nil
+}
+
+// exists returns true of the given path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
+// debugf logs the debug message if the library has a logger
+func (l *Library) debugf(format string, a ...interface{}) {
+	if l.Logger != nil {
+		l.Logger.Output(2, fmt.Sprintf(format, a...))
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package kconf
+
+import (
+	"os"
+	"path"
+	"syscall"
+)
+
+// Lock takes an exclusive advisory lock on the library (waits if it's taken), the returned function releases it
+func (l *Library) Lock() (func(), error) {
+	file := path.Join(l.Path, lockFile)
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_RDWR, metadataFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	l.debugf("locking %s", file)
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		l.debugf("unlocking %s", file)
+		// closing the file releases the lock
+		f.Close()
+	}, nil
+}
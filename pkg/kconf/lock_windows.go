@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package kconf
+
+// Lock is a no-op: the advisory locks are not supported on Windows
+func (l *Library) Lock() (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,172 @@
+package kconf
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+const (
+	// MetadataFile is the file of the library directory storing the metadata by default
+	MetadataFile                 = ".kconf.json"
+	metadataFileMode os.FileMode = 0644
+)
+
+// Metadata is the library state stored next to the kubeconfig symlinks
+type Metadata struct {
+	Entries map[string]*EntryMeta `json:"entries"`
+	// Default is the name of the entry activated when none is given
+	Default string `json:"default,omitempty"`
+	// Profiles are the named combinations of an entry, a context and a namespace
+	Profiles map[string]*Profile `json:"profiles,omitempty"`
+	// Recent are the last switches, the latest first
+	Recent []*Switch `json:"recent,omitempty"`
+	// Trash are the entries quarantined because their kubeconfigs disappeared, by name
+	Trash map[string]*TrashedEntry `json:"trash,omitempty"`
+}
+
+// Switch is an activation of an entry
+type Switch struct {
+	Time  time.Time `json:"time"`
+	Entry string    `json:"entry"`
+	// Profile is the profile the entry was activated by, if any
+	Profile string `json:"profile,omitempty"`
+	// Context is the context selected by the entry/context name, if any
+	Context string `json:"context,omitempty"`
+}
+
+// Profile selects an entry along with its context and namespace
+type Profile struct {
+	Entry string `json:"entry"`
+	// Context is the context of the entry, its current context if empty
+	Context string `json:"context,omitempty"`
+	// Namespace is the namespace of the context, its namespace if empty
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// EntryMeta holds the metadata of a single library entry
+type EntryMeta struct {
+	Index    int               `json:"index"`
+	AddedAt  time.Time         `json:"added_at"`
+	LastUsed time.Time         `json:"last_used"`
+	Aliases  []string          `json:"aliases,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	// Protected entries are removed only if forced
+	Protected bool `json:"protected,omitempty"`
+	// Checksum is the SHA-256 of the kubeconfig content when the entry was added or updated
+	Checksum string `json:"checksum,omitempty"`
+	// History are the previous contents of the kubeconfig
+	History []*Snapshot `json:"history,omitempty"`
+	// Switches is the number of times the entry was set
+	Switches int `json:"switches,omitempty"`
+	// Tunnel is the SSH tunnel the API server is reached through
+	Tunnel *Tunnel `json:"tunnel,omitempty"`
+	// Proxy is the URL of the proxy the API server is reached through
+	Proxy string `json:"proxy,omitempty"`
+	// Overlay are the pieces the kubeconfig is generated from
+	Overlay *Overlay `json:"overlay,omitempty"`
+	// Env are the environment variables set along with KUBECONFIG
+	Env map[string]string `json:"env,omitempty"`
+	// Patch is the strategic merge or JSON patch applied to the kubeconfig by set and exec
+	Patch string `json:"patch,omitempty"`
+	// MissingSince is when the kubeconfig was first found missing, nil while it exists
+	MissingSince *time.Time `json:"missing_since,omitempty"`
+}
+
+// Overlay is a kubeconfig generated from the clusters and contexts of a base entry and the credentials of a user file
+type Overlay struct {
+	// Base is the name of the entry the clusters and contexts come from
+	Base string `json:"base"`
+	// UserFile is the kubeconfig the credentials come from
+	UserFile string `json:"user_file"`
+	// User is the name of the user of UserFile, its first user if empty
+	User string `json:"user,omitempty"`
+}
+
+// Tunnel is an SSH tunnel to the API server through a jump host
+type Tunnel struct {
+	// Host is the jump host as given to ssh ([user@]host)
+	Host string `json:"host"`
+	// LocalPort is the local end of the tunnel, a free port is used if 0
+	LocalPort int `json:"local_port,omitempty"`
+	// Remote is the host:port of the API server as seen from the jump host, the server of the kubeconfig if empty
+	Remote string `json:"remote,omitempty"`
+}
+
+// Store persists the metadata of a library
+type Store interface {
+	// Load returns the stored metadata (empty metadata if nothing is stored yet)
+	Load() (*Metadata, error)
+	// Save replaces the stored metadata
+	Save(*Metadata) error
+}
+
+// FileStore stores the metadata in a JSON file
+type FileStore struct {
+	File string
+}
+
+// Load reads the metadata file (empty metadata if it doesn't exist)
+func (s *FileStore) Load() (*Metadata, error) {
+	meta := &Metadata{Entries: map[string]*EntryMeta{}}
+
+	data, err := os.ReadFile(s.File)
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+	if meta.Entries == nil {
+		meta.Entries = map[string]*EntryMeta{}
+	}
+	return meta, nil
+}
+
+// Save writes the metadata file atomically
+func (s *FileStore) Save(meta *Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.File, data, metadataFileMode)
+}
+
+// Entry returns the metadata of the given entry (creates it if doesn't exist)
+func (m *Metadata) Entry(name string) *EntryMeta {
+	e, ok := m.Entries[name]
+	if !ok {
+		e = &EntryMeta{}
+		m.Entries[name] = e
+	}
+	return e
+}
+
+// AliasOwner returns the name of the entry which has the given alias (empty if none)
+func (m *Metadata) AliasOwner(alias string) string {
+	for name, e := range m.Entries {
+		for _, a := range e.Aliases {
+			if a == alias {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// FreeIndex returns the lowest index not taken by any entry
+func (m *Metadata) FreeIndex() int {
+	taken := map[int]bool{}
+	for _, e := range m.Entries {
+		taken[e.Index] = true
+	}
+	idx := 1
+	for taken[idx] {
+		idx++
+	}
+	return idx
+}
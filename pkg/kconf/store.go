@@ -0,0 +1,70 @@
+package kconf
+
+import (
+	"crypto/sha256"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// blobDir is the directory of the library storing the kubeconfig contents by their checksum
+	blobDir      = ".store"
+	blobFileMode = 0600
+)
+
+// storeBlob stores the content of the file in the library and returns the path of the blob.
+// The same content is stored once whatever the number of the entries referencing it.
+func (l *Library) storeBlob(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return l.storeData(data)
+}
+
+// storeData stores the content in the library and returns the path of the blob
+func (l *Library) storeData(data []byte) (string, error) {
+	dir := path.Join(l.Path, blobDir)
+	if err := os.MkdirAll(dir, dirFileMode); err != nil {
+		return "", err
+	}
+
+	blob := l.blobPath(checksum(data))
+	if exists(blob) {
+		l.debugf("blob %s already stored", blob)
+		return blob, nil
+	}
+	l.debugf("storing blob %s", blob)
+	// the kubeconfigs hold credentials
+	return blob, WriteFileAtomic(blob, data, blobFileMode)
+}
+
+// blobPath returns the path of the blob with the given checksum
+func (l *Library) blobPath(sum string) string {
+	return path.Join(l.Path, blobDir, sum)
+}
+
+// IsChecksum returns true if the string is a hex encoded SHA-256, the name of a stored content
+func IsChecksum(s string) bool {
+	if len(s) != 2*sha256.Size {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// HasSnapshot returns true if the content of the given checksum is stored in the library
+func (l *Library) HasSnapshot(sum string) bool {
+	return IsChecksum(sum) && exists(l.blobPath(sum))
+}
+
+// isBlob returns true if the file is stored in the library
+func (l *Library) isBlob(file string) bool {
+	dir, err := filepath.Abs(path.Join(l.Path, blobDir))
+	return err == nil && filepath.Dir(file) == dir
+}
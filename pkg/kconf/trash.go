@@ -0,0 +1,69 @@
+package kconf
+
+import (
+	"os"
+	"path"
+	"time"
+)
+
+// TrashedEntry is an entry removed from the library with what is needed to restore it
+type TrashedEntry struct {
+	// Link is the target of the entry link as it was stored, relative or not
+	Link string `json:"link"`
+	// TrashedAt is when the entry was moved to the trash
+	TrashedAt time.Time  `json:"trashed_at"`
+	Meta      *EntryMeta `json:"meta"`
+}
+
+// Trash moves the entry to the trash of the metadata: its link is removed, its metadata is kept to restore it.
+// The caller saves the metadata.
+func (l *Library) Trash(e *Entry, meta *Metadata) error {
+	link, err := os.Readlink(e.Path)
+	if err != nil {
+		return err
+	}
+	if _, err = l.Remove(e, meta); err != nil {
+		return err
+	}
+	if meta.Trash == nil {
+		meta.Trash = map[string]*TrashedEntry{}
+	}
+	meta.Trash[e.Name] = &TrashedEntry{Link: link, TrashedAt: time.Now(), Meta: e.Meta}
+	return nil
+}
+
+// Restore puts the trashed entry back to the library, with another index if its one was taken.
+// The caller saves the metadata.
+func (l *Library) Restore(name string, meta *Metadata) error {
+	t, ok := meta.Trash[name]
+	if !ok {
+		return EntryError(name, NotFoundErrorf("kubeconfig not found in trash: %s", name))
+	}
+	if _, ok = meta.Entries[name]; ok {
+		return EntryError(name, InvalidErrorf("kubeconfig already exists: %s", name))
+	}
+	linkPath := path.Join(l.Path, name)
+	// a broken link is an entry as well
+	if _, err := os.Lstat(linkPath); err == nil {
+		return EntryError(name, InvalidErrorf("kubeconfig already exists: %s", name))
+	}
+
+	if err := os.MkdirAll(path.Dir(linkPath), dirFileMode); err != nil {
+		return err
+	}
+	l.debugf("restore symlink %s -> %s", linkPath, t.Link)
+	if err := os.Symlink(t.Link, linkPath); err != nil {
+		return err
+	}
+	for _, e := range meta.Entries {
+		if e.Index == t.Meta.Index {
+			t.Meta.Index = meta.FreeIndex()
+			break
+		}
+	}
+	// the kubeconfig is checked again
+	t.Meta.MissingSince = nil
+	meta.Entries[name] = t.Meta
+	delete(meta.Trash, name)
+	return nil
+}
@@ -0,0 +1,41 @@
+package kconf
+
+import "os"
+
+// Results of the verification of the entry content
+const (
+	// VerifyOK is the result of the entries with the content recorded when added
+	VerifyOK = "ok"
+	// VerifyChanged is the result of the entries whose kubeconfig was changed since added
+	VerifyChanged = "changed"
+	// VerifyMissing is the result of the entries whose kubeconfig disappeared
+	VerifyMissing = "missing"
+	// VerifyUnknown is the result of the entries added before the content was recorded
+	VerifyUnknown = "unknown"
+)
+
+// Verify compares the content of the entry kubeconfig with the one recorded when the entry was added or updated
+func (l *Library) Verify(e *Entry) string {
+	data, err := os.ReadFile(e.Path)
+	switch {
+	case err != nil:
+		l.debugf("cannot read %s: %v", e.Path, err)
+		return VerifyMissing
+	case e.Meta.Checksum == "":
+		return VerifyUnknown
+	case checksum(data) != e.Meta.Checksum:
+		return VerifyChanged
+	}
+	return VerifyOK
+}
+
+// record records the current content of the entry kubeconfig
+func (l *Library) record(linkPath string, meta *EntryMeta) {
+	data, err := os.ReadFile(linkPath)
+	if err != nil {
+		l.debugf("cannot record the content of %s: %v", linkPath, err)
+		meta.Checksum = ""
+		return
+	}
+	meta.Checksum = checksum(data)
+}
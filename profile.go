@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// manageProfile runs the profile operation given by the first argument
+func (c *Config) manageProfile(configPath string, args []string) error {
+	if len(args) == 0 {
+		return kconf.InvalidErrorf("unknown profile operation, expected: create, use, list, delete, export, import")
+	}
+	op, args := args[0], args[1:]
+
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	if meta.Profiles == nil {
+		meta.Profiles = map[string]*kconf.Profile{}
+	}
+
+	switch op {
+	case "create":
+		if len(args) != 1 || c.Entry == "" {
+			return kconf.InvalidErrorf("expected the profile name and --entry")
+		}
+		return c.createProfile(entries, meta, args[0])
+	case "use":
+		if len(args) != 1 {
+			return kconf.InvalidErrorf("expected the profile name")
+		}
+		p, ok := meta.Profiles[args[0]]
+		if !ok {
+			return kconf.NotFoundErrorf("profile not found: %q", args[0])
+		}
+		e, err := kconf.FindName(entries, p.Entry)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", args[0], err)
+		}
+		return c.activate(configPath, e, meta, args[0], "")
+	case "list":
+		return c.printProfiles(meta.Profiles)
+	case "delete":
+		if len(args) != 1 {
+			return kconf.InvalidErrorf("expected the profile name")
+		}
+		if _, ok := meta.Profiles[args[0]]; !ok {
+			return kconf.NotFoundErrorf("profile not found: %q", args[0])
+		}
+		delete(meta.Profiles, args[0])
+		if err = c.Library.Save(meta); err != nil {
+			return err
+		}
+		c.infof("profile %s deleted\n", args[0])
+		return nil
+	case "export":
+		profiles := meta.Profiles
+		if len(args) > 0 {
+			profiles = map[string]*kconf.Profile{}
+			for _, name := range args {
+				p, ok := meta.Profiles[name]
+				if !ok {
+					return kconf.NotFoundErrorf("profile not found: %q", name)
+				}
+				profiles[name] = p
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(profiles)
+	case "import":
+		if len(args) != 1 {
+			return kconf.InvalidErrorf("expected the file of the exported profiles")
+		}
+		return c.importProfiles(meta, args[0])
+	}
+	return kconf.InvalidErrorf("unknown profile operation %q, expected: create, use, list, delete, export, import", op)
+}
+
+// createProfile adds the profile of the --entry, --context and --namespace flags
+func (c *Config) createProfile(entries []*kconf.Entry, meta *kconf.Metadata, name string) error {
+	if err := kconf.ValidateName(name); err != nil {
+		return err
+	}
+	if _, ok := meta.Profiles[name]; ok && !c.Force {
+		return kconf.InvalidErrorf("profile already exists: %q", name)
+	}
+	e, err := c.findEntry(entries, c.Entry)
+	if err != nil {
+		return err
+	}
+	if c.Context != "" {
+		kc, err := loadKubeconfig(e.Path)
+		if err != nil {
+			return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+		}
+		if kc.context(c.Context) == nil {
+			return kconf.EntryError(e.Name, kconf.NotFoundErrorf("context not found: %q", c.Context))
+		}
+	}
+
+	meta.Profiles[name] = &kconf.Profile{Entry: e.Name, Context: c.Context, Namespace: c.Namespace}
+	if err = c.Library.Save(meta); err != nil {
+		return err
+	}
+	c.infof("profile %s created\n", name)
+	return nil
+}
+
+// importProfiles adds the profiles exported to the file, the existing ones are replaced only if forced
+func (c *Config) importProfiles(meta *kconf.Metadata, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var profiles map[string]*kconf.Profile
+	if err = json.Unmarshal(data, &profiles); err != nil {
+		return kconf.InvalidErrorf("invalid profiles file: %w", err)
+	}
+
+	for name, p := range profiles {
+		if err = kconf.ValidateName(name); err != nil {
+			return err
+		}
+		if p == nil || p.Entry == "" {
+			return kconf.InvalidErrorf("profile %q has no entry", name)
+		}
+		if _, ok := meta.Profiles[name]; ok && !c.Force {
+			return kconf.InvalidErrorf("profile already exists: %q", name)
+		}
+	}
+	for name, p := range profiles {
+		meta.Profiles[name] = p
+	}
+	if err = c.Library.Save(meta); err != nil {
+		return err
+	}
+	c.infof("%d profiles imported\n", len(profiles))
+	return nil
+}
+
+// printProfiles prints the profiles sorted by name
+func (c *Config) printProfiles(profiles map[string]*kconf.Profile) error {
+	if c.Output == outputJSON {
+		return json.NewEncoder(os.Stdout).Encode(profiles)
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		p := profiles[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, p.Entry, orDash(p.Context), orDash(p.Namespace))
+	}
+	return w.Flush()
+}
+
+// orDash returns the string or a dash if it's empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// applyProfile writes the working copy of the variant of the entry kubeconfig file with the context and namespace of the profile
+func applyProfile(configPath string, e *kconf.Entry, file string, p *kconf.Profile, variant string) (string, error) {
+	kc, err := loadKubeconfig(file)
+	if err != nil {
+		return "", kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	if p.Context != "" {
+		kc.CurrentContext = p.Context
+	}
+	ctx := kc.context(kc.CurrentContext)
+	if ctx == nil {
+		return "", kconf.EntryError(e.Name, kconf.NotFoundErrorf("context not found: %q", kc.CurrentContext))
+	}
+	if p.Namespace != "" {
+		ctx.Namespace = p.Namespace
+	}
+
+	work := workFile(configPath, e.Name, variant)
+	return work, writeWorkCopy(work, kc, e)
+}
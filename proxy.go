@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// proxySchemes are the proxy URL schemes supported by kubectl
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true}
+
+// proxyKubeconfig sets the proxy of the entry or deletes it.
+// The proxy is applied by set and exec to a working copy of the kubeconfig, the kubeconfig itself is not changed.
+func (c *Config) proxyKubeconfig(configPath string, args []string) error {
+	if c.Delete {
+		if len(args) != 1 {
+			return kconf.InvalidErrorf("expected the kubeconfig name")
+		}
+	} else {
+		if len(args) != 2 {
+			return kconf.InvalidErrorf("expected the kubeconfig name and the proxy URL")
+		}
+		if err := validateProxy(args[1]); err != nil {
+			return err
+		}
+	}
+
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if c.Delete {
+			e.Meta.Proxy = ""
+		} else {
+			if e.Meta.Tunnel != nil {
+				return kconf.EntryError(e.Name, kconf.InvalidErrorf("kubeconfig %q has a tunnel", e.Name))
+			}
+			e.Meta.Proxy = args[1]
+		}
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+
+		if c.Delete {
+			c.infof("%s proxy removed\n", e.Name)
+		} else {
+			c.infof("%s proxied through %s\n", e.Name, args[1])
+		}
+		return nil
+	})
+}
+
+// validateProxy checks the proxy URL
+func validateProxy(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil || !proxySchemes[u.Scheme] || u.Host == "" {
+		return kconf.InvalidErrorf("invalid proxy URL %q: expected http, https or socks5 URL", proxy)
+	}
+	return nil
+}
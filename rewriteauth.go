@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// rewriteAuth replaces the static credentials of the kubeconfig user with the exec credential plugin given by --exec.
+// The previous content is kept in the history of the entry.
+func (c *Config) rewriteAuth(configPath string, args []string) error {
+	command := strings.Fields(c.Exec)
+	if len(command) == 0 {
+		return kconf.InvalidErrorf("--exec is required")
+	}
+
+	return c.makeKubeconfig(configPath, args, func(e *kconf.Entry, meta *kconf.Metadata) error {
+		kc, err := loadKubeconfig(e.Path)
+		if err != nil {
+			return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+		}
+
+		name := c.User
+		if name == "" {
+			ctx := kc.context(kc.CurrentContext)
+			if ctx == nil {
+				return kconf.EntryError(e.Name, kconf.InvalidErrorf("current context not found: %q", kc.CurrentContext))
+			}
+			name = ctx.User
+		}
+		user := kc.user(name)
+		if user == nil {
+			return kconf.EntryError(e.Name, kconf.NotFoundErrorf("user not found: %q", name))
+		}
+
+		if c.DryRun {
+			fmt.Printf("user %s of %s would be rewritten to exec %s\n", name, e.Name, strings.Join(command, " "))
+			return nil
+		}
+		*user = User{Exec: execPlugin(command), Extra: user.Extra}
+
+		data, err := kc.marshal()
+		if err != nil {
+			return err
+		}
+		if err = c.Library.Rewrite(e, data); err != nil {
+			return err
+		}
+		if err = c.Library.Save(meta); err != nil {
+			return err
+		}
+		c.infof("user %s of %s rewritten to exec %s\n", name, e.Name, strings.Join(command, " "))
+		return nil
+	})
+}
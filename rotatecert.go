@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	csrAPIPath = "/apis/certificates.k8s.io/v1/certificatesigningrequests"
+	// clientSigner signs the client certificates of the API server
+	clientSigner    = "kubernetes.io/kube-apiserver-client"
+	csrPollInterval = 2 * time.Second
+	defaultCSRWait  = 10 * time.Minute
+)
+
+// csrObject is the CertificateSigningRequest resource, the byte slices are base64 encoded in JSON like in the API
+type csrObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Request    []byte   `json:"request"`
+		SignerName string   `json:"signerName"`
+		Usages     []string `json:"usages"`
+	} `json:"spec"`
+	Status struct {
+		Certificate []byte `json:"certificate,omitempty"`
+		Conditions  []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// rotateCert replaces the client certificate of the current context user with a new one
+// signed through a CertificateSigningRequest submitted with the current credentials.
+// The library is locked only to store the new certificate, not while waiting for the approval:
+// the certificate and the key are then set in the kubeconfig read again, the changes made while waiting are kept.
+func (c *Config) rotateCert(configPath string, args []string) error {
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the kubeconfig name")
+	}
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	e, err := c.findEntry(entries, args[0])
+	if err != nil {
+		return err
+	}
+
+	kc, err := loadKubeconfig(e.Path)
+	if err != nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	ctx := kc.context(kc.CurrentContext)
+	if ctx == nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("current context not found: %q", kc.CurrentContext))
+	}
+	user := kc.user(ctx.User)
+	if user == nil {
+		return kconf.EntryError(e.Name, kconf.NotFoundErrorf("user not found: %q", ctx.User))
+	}
+	certPEM, err := dataOrFile(user.ClientCertificateData, user.ClientCertificate, filepath.Dir(kconf.Target(e.Path)))
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("no client certificate for user %q", ctx.User))
+	}
+	current, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("invalid client certificate: %w", err))
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	// the identity stays the same
+	subject := pkix.Name{CommonName: current.Subject.CommonName, Organization: current.Subject.Organization}
+	request, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: subject}, key)
+	if err != nil {
+		return err
+	}
+
+	csr := &csrObject{APIVersion: "certificates.k8s.io/v1", Kind: "CertificateSigningRequest"}
+	csr.Metadata.Name = fmt.Sprintf("kconf-%s-%d", unsafeNameChars.ReplaceAllString(strings.ToLower(subject.CommonName), "-"), time.Now().Unix())
+	csr.Spec.Request = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request})
+	csr.Spec.SignerName = clientSigner
+	csr.Spec.Usages = []string{"client auth"}
+	if c.DryRun {
+		fmt.Printf("CSR %s for %s would be submitted\n", csr.Metadata.Name, subject.CommonName)
+		return nil
+	}
+
+	client, err := newAPIClient(kc, e.Path, c.Net)
+	if err != nil {
+		return err
+	}
+	cert, err := c.signCSR(client, csr)
+	if err != nil {
+		return err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	unlock, err := c.Library.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	name := e.Name
+	return c.makeKubeconfig(configPath, []string{name}, func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if e.Name != name {
+			return kconf.EntryError(name, kconf.NotFoundErrorf("kubeconfig removed while waiting for the certificate: %q", name))
+		}
+		kc, err := loadKubeconfig(e.Path)
+		if err != nil {
+			return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+		}
+		user := kc.user(ctx.User)
+		if user == nil {
+			return kconf.EntryError(e.Name, kconf.NotFoundErrorf("user %q removed while waiting for the certificate", ctx.User))
+		}
+		user.ClientCertificate, user.ClientKey = "", ""
+		user.ClientCertificateData = base64.StdEncoding.EncodeToString(cert)
+		user.ClientKeyData = base64.StdEncoding.EncodeToString(keyPEM)
+		data, err := kc.marshal()
+		if err != nil {
+			return err
+		}
+		if err := c.Library.Rewrite(e, data); err != nil {
+			return err
+		}
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+		c.infof("%s client certificate rotated\n", e.Name)
+		return nil
+	})
+}
+
+// signCSR submits the CSR and returns the PEM certificate once it's approved and signed, waiting at most --wait
+func (c *Config) signCSR(client *apiClient, csr *csrObject) ([]byte, error) {
+	body, err := json.Marshal(csr)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.call(http.MethodPost, csrAPIPath, body, http.StatusCreated, &csrObject{}); err != nil {
+		return nil, fmt.Errorf("cannot submit CSR: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "waiting for the approval of CSR %s (kubectl certificate approve %s)\n", csr.Metadata.Name, csr.Metadata.Name)
+
+	deadline := time.Now().Add(c.Wait)
+	for {
+		res := &csrObject{}
+		if err = client.call(http.MethodGet, csrAPIPath+"/"+csr.Metadata.Name, nil, http.StatusOK, res); err != nil {
+			return nil, err
+		}
+		for _, cond := range res.Status.Conditions {
+			if cond.Type == "Denied" || cond.Type == "Failed" {
+				return nil, fmt.Errorf("CSR %s %s: %s", csr.Metadata.Name, strings.ToLower(cond.Type), cond.Message)
+			}
+		}
+		if len(res.Status.Certificate) > 0 {
+			return res.Status.Certificate, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("CSR %s not signed after %s", csr.Metadata.Name, c.Wait)
+		}
+		time.Sleep(csrPollInterval)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// the schemes of the secret references resolved by the password manager CLIs
+const (
+	onePasswordScheme = "op://"
+	bitwardenScheme   = "bw://"
+	// defaultBitwardenField is the field of the Bitwarden item given without field
+	defaultBitwardenField = "password"
+)
+
+// hasSecretRefs returns true if the kubeconfig content may hold secret references
+func hasSecretRefs(data []byte) bool {
+	return bytes.Contains(data, []byte(onePasswordScheme)) || bytes.Contains(data, []byte(bitwardenScheme))
+}
+
+// isSecretRef returns true if the value is a reference to a secret of a password manager
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, onePasswordScheme) || strings.HasPrefix(value, bitwardenScheme)
+}
+
+// resolveSecrets replaces the secret references of the credentials of the users with the secrets,
+// each reference is resolved once
+func resolveSecrets(kc *Kubeconfig) error {
+	resolved := map[string]string{}
+	resolve := func(value *string) error {
+		if !isSecretRef(*value) {
+			return nil
+		}
+		secret, ok := resolved[*value]
+		if !ok {
+			var err error
+			if secret, err = resolveSecret(*value); err != nil {
+				return err
+			}
+			resolved[*value] = secret
+		}
+		*value = secret
+		return nil
+	}
+
+	for i := range kc.Users {
+		u := &kc.Users[i].User
+		fields := []*string{&u.Token, &u.Username, &u.Password, &u.ClientCertificateData, &u.ClientKeyData}
+		if u.Exec != nil {
+			for j := range u.Exec.Env {
+				fields = append(fields, &u.Exec.Env[j].Value)
+			}
+		}
+		for _, field := range fields {
+			if err := resolve(field); err != nil {
+				return fmt.Errorf("user %s: %w", kc.Users[i].Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSecret returns the secret the reference points to: op://vault/item/field is read with the 1Password CLI,
+// bw://item[/field] with the Bitwarden CLI (the password by default)
+func resolveSecret(ref string) (string, error) {
+	var cmd *exec.Cmd
+	if strings.HasPrefix(ref, onePasswordScheme) {
+		cmd = exec.Command("op", "read", "--no-newline", ref)
+	} else {
+		item, field := strings.TrimPrefix(ref, bitwardenScheme), defaultBitwardenField
+		if i := strings.LastIndex(item, "/"); i >= 0 {
+			item, field = item[:i], item[i+1:]
+		}
+		item, err := url.PathUnescape(item)
+		if err != nil || item == "" {
+			return "", fmt.Errorf("invalid secret reference %s", ref)
+		}
+		cmd = exec.Command("bw", "get", field, item)
+	}
+
+	debugf("resolving %s with %s", ref, cmd.Args[0])
+	// the CLIs may ask to sign in
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %s: %w", ref, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
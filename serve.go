@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const defaultListenAddress = "localhost:9090"
+
+// serveMetrics exposes the library metrics in the Prometheus text format until interrupted
+func (c *Config) serveMetrics(configPath string, args []string) error {
+	if len(args) > 0 {
+		return kconf.InvalidErrorf("unexpected arguments: %v", args)
+	}
+
+	// the scrapes share the cluster cache
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		data, err := c.metrics(configPath)
+		if err != nil {
+			debugf("cannot collect metrics: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write(data)
+	})
+
+	c.infof("serving metrics on http://%s/metrics\n", c.Listen)
+	return http.ListenAndServe(c.Listen, mux)
+}
+
+// metrics returns the current library metrics in the Prometheus text format
+func (c *Config) metrics(configPath string) ([]byte, error) {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return nil, err
+	}
+	infos := loadEntryInfos(configPath, entries)
+	clusters := c.clusterData(configPath, entries, infos)
+
+	broken := 0
+	for _, e := range entries {
+		if infos[e.Name].Error != "" {
+			broken++
+		}
+	}
+
+	var buf bytes.Buffer
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("kconf_entries", "gauge", "Number of the kubeconfigs in the library.")
+	fmt.Fprintf(&buf, "kconf_entries %d\n", len(entries))
+
+	metric("kconf_broken_entries", "gauge", "Number of the kubeconfigs which cannot be read or parsed.")
+	fmt.Fprintf(&buf, "kconf_broken_entries %d\n", broken)
+
+	metric("kconf_switches_total", "counter", "Number of times the kubeconfig was set.")
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "kconf_switches_total{entry=%s} %d\n", strconv.Quote(e.Name), e.Meta.Switches)
+	}
+
+	metric("kconf_api_up", "gauge", "Whether the API server of the kubeconfig answered the last probe.")
+	for _, e := range entries {
+		up := 0
+		if !isDown(clusters[e.Name].Status.Status) {
+			up = 1
+		}
+		fmt.Fprintf(&buf, "kconf_api_up{entry=%s} %d\n", strconv.Quote(e.Name), up)
+	}
+
+	metric("kconf_api_latency_seconds", "gauge", "Latency of the last probe of the API server of the kubeconfig.")
+	for _, e := range entries {
+		if latency := clusters[e.Name].Status.Latency; latency != 0 {
+			fmt.Fprintf(&buf, "kconf_api_latency_seconds{entry=%s} %g\n", strconv.Quote(e.Name), latency.Seconds())
+		}
+	}
+	return buf.Bytes(), nil
+}
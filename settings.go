@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const settingsFile = ".config.json"
+
+// Settings are the user preferences read from the config file of the library
+type Settings struct {
+	// IgnoreCase makes the name resolution case-insensitive
+	IgnoreCase bool `json:"ignore_case"`
+	// RelativeLinks stores the links relative to the library directory
+	RelativeLinks bool `json:"relative_links"`
+	// Copy stores the copies of the added kubeconfigs in the library
+	Copy bool `json:"copy"`
+	// CacheTTL is the duration the data fetched from the clusters stays cached (1h if not set)
+	CacheTTL string `json:"cache_ttl"`
+	// Network configures the requests sent to the API servers
+	Network NetworkSettings `json:"network"`
+	// Watch configures the names of the kubeconfigs added by watch
+	Watch WatchSettings `json:"watch"`
+	// Credentials are the token sources of the credential command, by name
+	Credentials map[string]CredentialSource `json:"credentials"`
+	// Launchers are the command line templates of the tools run by run, by tool name
+	Launchers map[string]string `json:"launchers"`
+	// Theme configures the human readable output
+	Theme Theme `json:"theme"`
+	// Guard configures the confirmation required to activate some entries
+	Guard GuardSettings `json:"guard"`
+	// Notify configures the desktop notifications sent when some entries are activated
+	Notify NotifySettings `json:"notify"`
+	// Trash configures the quarantine of the entries whose kubeconfigs disappeared
+	Trash TrashSettings `json:"trash"`
+}
+
+// Theme is the styling of the human readable output
+type Theme struct {
+	// ActiveMarker marks the active entry ("*" if not set)
+	ActiveMarker string `json:"active_marker"`
+	// ActiveColor is the color of the active entry
+	ActiveColor string `json:"active_color"`
+	// TagColors are the colors of the entries by their key=value tags
+	TagColors map[string]string `json:"tag_colors"`
+	// Padding is the number of spaces between the table columns (2 if not set)
+	Padding int `json:"padding"`
+}
+
+// GuardSettings select the entries which require a confirmation to be activated
+type GuardSettings struct {
+	// Tags select the guarded entries having any of them (env=prod if not set)
+	Tags map[string]string `json:"tags"`
+	// Banner prints a red banner when a guarded entry is activated
+	Banner bool `json:"banner"`
+}
+
+// NotifySettings select the entries whose activation sends a desktop notification
+type NotifySettings struct {
+	// Tags select the entries having any of them (none if not set)
+	Tags map[string]string `json:"tags"`
+}
+
+// TrashSettings configure the automatic trash of the missing kubeconfigs
+type TrashSettings struct {
+	// MissingDays is the number of days a kubeconfig is missing before its entry is moved to the trash (never if not set)
+	MissingDays int `json:"missing_days"`
+}
+
+// NetworkSettings are the defaults of the network flags
+type NetworkSettings struct {
+	// Timeout is the timeout of a single request (3s if not set)
+	Timeout string `json:"timeout"`
+	// Retries is the number of times the failed requests are retried
+	Retries int `json:"retries"`
+	// Workers is the number of the API servers queried concurrently (8 if not set)
+	Workers int `json:"workers"`
+}
+
+// WatchSettings are the rules of the kubeconfigs added from the watched directory
+type WatchSettings struct {
+	// Pattern selects the added files by their names ("*" if not set)
+	Pattern string `json:"pattern"`
+	// Prefix is prepended to the entry names, "dir/" puts the entries in a subdirectory
+	Prefix string `json:"prefix"`
+}
+
+// loadSettings reads the config file of the config directory (default settings if it doesn't exist)
+func loadSettings(configPath string) (*Settings, error) {
+	settings := &Settings{}
+
+	file := path.Join(configPath, settingsFile)
+	if settingsDir != "" {
+		file = path.Join(settingsDir, xdgSettingsFile)
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		debugf("no config file %s, using defaults", file)
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, settings); err != nil {
+		return nil, err
+	}
+	debugf("read config file %s", file)
+	return settings, nil
+}
+
+// applyNetworkSettings uses the network settings for the network flags not given on the command line
+func (c *Config) applyNetworkSettings() error {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	network := c.Settings.Network
+	if !set["timeout"] && network.Timeout != "" {
+		timeout, err := time.ParseDuration(network.Timeout)
+		if err != nil {
+			return kconf.InvalidErrorf("invalid network timeout %q: %w", network.Timeout, err)
+		}
+		c.Net.Timeout = timeout
+	}
+	if !set["retries"] && network.Retries != 0 {
+		c.Net.Retries = network.Retries
+	}
+	if !set["workers"] && network.Workers != 0 {
+		c.Net.Workers = network.Workers
+	}
+	return c.Net.validate()
+}
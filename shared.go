@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"path"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	// sharedMarker is the file marking the library root shared by several users, each one gets a sub-library of their own
+	sharedMarker                = ".shared"
+	userDirFileMode os.FileMode = 0700
+)
+
+// userLibrary returns the sub-library of the invoking user in the shared library root (creates it if doesn't exist).
+// The sub-library must belong to the user, it's made private to them.
+func userLibrary(root string) (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	// DOMAIN\user on Windows
+	name := u.Username[strings.LastIndex(u.Username, `\`)+1:]
+	dir := path.Join(root, name)
+
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		debugf("creating user library %s", dir)
+		return dir, os.Mkdir(dir, userDirFileMode)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", kconf.InvalidErrorf("user library %s is not a directory", dir)
+	}
+	if err = checkOwner(dir, info); err != nil {
+		return "", err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		debugf("restricting permissions of %s", dir)
+		if err = os.Chmod(dir, userDirFileMode); err != nil {
+			return "", err
+		}
+	}
+	debugf("user library: %s", dir)
+	return dir, nil
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// checkOwner fails if the directory doesn't belong to the invoking user
+func checkOwner(dir string, info os.FileInfo) error {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("user library %s belongs to uid %d: %w", dir, st.Uid, fs.ErrPermission)
+	}
+	return nil
+}
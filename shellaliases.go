@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+var (
+	// unsafeAliasChars are the characters not allowed in the shell alias names
+	unsafeAliasChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+	// safeShellWord matches the words which need no quoting
+	safeShellWord = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+-]+$`)
+)
+
+// printShellAliases prints the shell aliases setting the entries, to be sourced from the shell rc file.
+// Each entry gets the alias of its name and of its kconf aliases, prefixed with "k".
+func (c *Config) printShellAliases(configPath string, args []string) error {
+	var selector map[string]string
+	if c.Tag != "" {
+		k, v, err := parseTag(c.Tag)
+		if err != nil {
+			return err
+		}
+		selector = map[string]string{k: v}
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	prog := path.Base(os.Args[0])
+	for _, e := range entries {
+		if selector != nil && !matchTags(e.Meta.Tags, selector) {
+			continue
+		}
+		cmd := fmt.Sprintf(`eval "$(%s set %s)"`, prog, shellQuote(e.Name))
+		for _, name := range append([]string{e.Name}, e.Meta.Aliases...) {
+			fmt.Printf("alias %s=%s\n", c.AliasPrefix+unsafeAliasChars.ReplaceAllString(name, "_"), shellQuote(cmd))
+		}
+	}
+	return nil
+}
+
+// shellQuote returns the string single-quoted for the POSIX shells if needed
+func shellQuote(s string) string {
+	if safeShellWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// parseTag splits the key=value tag
+func parseTag(arg string) (string, string, error) {
+	kv := strings.SplitN(arg, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return "", "", kconf.InvalidErrorf("invalid tag %q, expected key=value", arg)
+	}
+	return kv[0], kv[1], nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	stateDir = ".state"
+	// stateUserFile is the state file outside the sessions
+	stateUserFile = "user"
+	stateFileMode = 0600
+	// sessionVar names the session of the shell, each session has its own active entry
+	sessionVar = "KCONF_SESSION"
+)
+
+// activeState is the entry last activated by set
+type activeState struct {
+	Entry   string `json:"entry"`
+	Profile string `json:"profile,omitempty"`
+	// Context is the context selected by the entry/context name, if any
+	Context string    `json:"context,omitempty"`
+	File    string    `json:"file"`
+	Time    time.Time `json:"time"`
+	// Previous is the entry active before, the one of set -
+	Previous string `json:"previous,omitempty"`
+}
+
+// sessionName returns the name of the session of the shell, the user one outside the sessions
+func sessionName() string {
+	if session := os.Getenv(sessionVar); session != "" {
+		if strings.ContainsAny(session, `/\`) || strings.HasPrefix(session, ".") {
+			debugf("ignoring invalid session %q", session)
+		} else {
+			return "session-" + session
+		}
+	}
+	return stateUserFile
+}
+
+// stateFile returns the state file of the session, the user one outside the sessions
+func stateFile(configPath string) string {
+	name := sessionName()
+	if stateRoot != "" {
+		return path.Join(stateRoot, name+".json")
+	}
+	return path.Join(configPath, stateDir, name+".json")
+}
+
+// loadState reads the state of the session (empty if it doesn't exist or is corrupted)
+func loadState(configPath string) *activeState {
+	st := &activeState{}
+	file := stateFile(configPath)
+	if data, err := os.ReadFile(file); err == nil {
+		if err = json.Unmarshal(data, st); err != nil {
+			debugf("ignoring corrupted state %s: %v", file, err)
+		}
+	}
+	return st
+}
+
+// sessionStates returns the states of all the sessions, the corrupted ones are skipped
+func sessionStates(configPath string) []*activeState {
+	dir := path.Dir(stateFile(configPath))
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var res []*activeState
+	for _, f := range files {
+		if f.IsDir() || path.Ext(f.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(path.Join(dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		st := &activeState{}
+		if err = json.Unmarshal(data, st); err == nil {
+			res = append(res, st)
+		}
+	}
+	return res
+}
+
+// save writes the state of the session
+func (st *activeState) save(configPath string) error {
+	file := stateFile(configPath)
+	if err := os.MkdirAll(path.Dir(file), confDirFileMode); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return kconf.WriteFileAtomic(file, data, stateFileMode)
+}
+
+// recordActive records the entry activated by set in the state of the session
+func recordActive(configPath string, e *kconf.Entry, profile, context, file string) error {
+	st := loadState(configPath)
+	if st.Entry != e.Name || st.Context != context {
+		st.Previous = st.Entry
+		if st.Context != "" {
+			st.Previous += "/" + st.Context
+		}
+	}
+	st.Entry, st.Profile, st.Context, st.File, st.Time = e.Name, profile, context, file, e.Meta.LastUsed
+	return st.save(configPath)
+}
+
+// kubeconfigPaths returns the files of the KUBECONFIG list, none if it's not set
+func kubeconfigPaths() []string {
+	var res []string
+	for _, file := range filepath.SplitList(os.Getenv(kubeConfigVar)) {
+		if file != "" {
+			res = append(res, file)
+		}
+	}
+	return res
+}
+
+// envKubeconfig returns the file KUBECONFIG points to, empty if it's not set.
+// For a list, it's the file kubectl takes the current context from: the first one setting it.
+func envKubeconfig() string {
+	files := kubeconfigPaths()
+	switch len(files) {
+	case 0:
+		return ""
+	case 1:
+		return files[0]
+	}
+	for _, file := range files {
+		if kc, err := loadKubeconfig(file); err == nil && kc.CurrentContext != "" {
+			return file
+		}
+	}
+	return files[0]
+}
+
+// activeContext returns the current context of the kubeconfig used for the active entry: KUBECONFIG, the file recorded in the state or the entry itself
+func activeContext(configPath string, e *kconf.Entry) string {
+	file := envKubeconfig()
+	if file == "" {
+		file = e.Path
+		if st := loadState(configPath); st.Entry == e.Name && st.File != "" {
+			file = st.File
+		}
+	}
+	kc, err := loadKubeconfig(file)
+	if err != nil {
+		return ""
+	}
+	return kc.CurrentContext
+}
+
+// activeEntry returns the active entry (nil if none): the one KUBECONFIG points to, directly, through a working copy or to its file,
+// the one of the current context for a list. Without KUBECONFIG, it's the entry recorded in the state of the session, the standard kubeconfig otherwise.
+func activeEntry(configPath string, entries []*kconf.Entry) *kconf.Entry {
+	file := envKubeconfig()
+	if file == "" {
+		if st := loadState(configPath); st.Entry != "" {
+			for _, e := range entries {
+				if e.Name == st.Entry {
+					return e
+				}
+			}
+		}
+		// kubectl falls back to it
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		file = filepath.Join(home, kubeDefaultConfig)
+	}
+
+	if name := workEntry(configPath, file); name != "" {
+		file = path.Join(configPath, name)
+	}
+	for _, e := range entries {
+		if e.Path == file {
+			return e
+		}
+	}
+	// set by other means than set
+	real := kconf.Target(file)
+	for _, e := range entries {
+		if kconf.Target(e.Path) == real {
+			return e
+		}
+	}
+	return nil
+}
+
+// hintOutside tells on stderr that KUBECONFIG is set to a kubeconfig which is not in the library, if it is
+func (c *Config) hintOutside(active *kconf.Entry) {
+	file := os.Getenv(kubeConfigVar)
+	if active != nil || file == "" || c.Quiet {
+		return
+	}
+	if len(kubeconfigPaths()) > 1 {
+		fmt.Fprintf(os.Stderr, "%s=%s is not in the library, add it with kconf adopt --merge or --each\n", kubeConfigVar, file)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s=%s is not in the library, add it with kconf adopt\n", kubeConfigVar, file)
+}
+
+// printCurrent prints the name of the active entry
+func (c *Config) printCurrent(configPath string, _ []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	if implicit := kubeDefaultEntry(entries); implicit != nil {
+		entries = append(entries, implicit)
+	}
+
+	e := activeEntry(configPath, entries)
+	c.hintOutside(e)
+	if e == nil {
+		return kconf.NotFoundErrorf("no active kubeconfig")
+	}
+	fmt.Println(e.Name)
+	return nil
+}
+
+// unsetKubeconfig prints the shell commands unsetting KUBECONFIG and the variables of the active entry, and clears the state of the session.
+// set - switches back to the entry.
+func (c *Config) unsetKubeconfig(configPath string, _ []string) error {
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	return c.deactivate(configPath, activeEntry(configPath, entries), meta)
+}
+
+// deactivate prints the shell commands unsetting KUBECONFIG and the variables of the entry if any, closes its tunnel
+// and clears the state of the session
+func (c *Config) deactivate(configPath string, e *kconf.Entry, meta *kconf.Metadata) error {
+	if e != nil {
+		if e.Meta.Tunnel != nil {
+			c.closeTunnel(configPath, e.Name, e.Meta.Tunnel)
+		}
+		for _, kv := range entryEnv(e.Meta) {
+			fmt.Println(c.unsetCommand(strings.SplitN(kv, "=", 2)[0]))
+		}
+	}
+	fmt.Println(c.unsetCommand(kubeConfigVar))
+
+	st := loadState(configPath)
+	if st.Entry == "" {
+		return nil
+	}
+	// the working copy may hold resolved secrets, the tunnel one is gone with the tunnel
+	if workEntry(configPath, st.File) != "" {
+		debugf("removing working copy %s", st.File)
+		os.Remove(st.File)
+	}
+	if _, ok := meta.Entries[st.Entry]; ok {
+		st.Previous = st.Entry
+		if st.Context != "" {
+			st.Previous += "/" + st.Context
+		}
+	}
+	st.Entry, st.Profile, st.Context, st.File = "", "", "", ""
+	return st.save(configPath)
+}
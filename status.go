@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// printStatus prints the active entry (or the kubeconfig outside the library KUBECONFIG points to) with its file, context, namespace and credentials,
+// and the reachability of the API server if requested
+func (c *Config) printStatus(configPath string, _ []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	if implicit := kubeDefaultEntry(entries); implicit != nil {
+		entries = append(entries, implicit)
+	}
+	e := activeEntry(configPath, entries)
+	c.hintOutside(e)
+	// the working copy or the profile file has the context actually used
+	file := envKubeconfig()
+	name := "- (not in the library)"
+	if e != nil {
+		name = e.Name
+		if file == "" {
+			file = e.Path
+		}
+	} else if file == "" {
+		return kconf.NotFoundErrorf("no active kubeconfig")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "kubeconfig\t%s\n", name)
+	files := kubeconfigPaths()
+	var kc *Kubeconfig
+	if len(files) > 1 {
+		// kubectl sees the merged kubeconfigs
+		fmt.Fprintf(w, "file\t%s (merged)\n", os.Getenv(kubeConfigVar))
+		kc, err = mergeKubeconfigs(files)
+	} else {
+		fmt.Fprintf(w, "file\t%s\n", describeFile(file))
+		kc, err = loadKubeconfig(file)
+	}
+	if err != nil {
+		fmt.Fprintf(w, "error\tcannot parse kubeconfig: %v\n", err)
+		return w.Flush()
+	}
+	fmt.Fprintf(w, "context\t%s\n", orDash(kc.CurrentContext))
+	if ctx := kc.context(kc.CurrentContext); ctx != nil {
+		namespace := ctx.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		fmt.Fprintf(w, "namespace\t%s\n", namespace)
+		if cluster := kc.cluster(ctx.Cluster); cluster != nil {
+			fmt.Fprintf(w, "server\t%s\n", orDash(cluster.Server))
+		}
+		if user := kc.user(ctx.User); user != nil {
+			fmt.Fprintf(w, "user\t%s (%s)\n", ctx.User, credentialExpiry(user, filepath.Dir(kconf.Target(file))))
+		}
+	}
+	if c.Status {
+		res := probe(file, c.Net)
+		status := res.Status
+		if res.Error != "" {
+			status += ": " + res.Error
+		} else {
+			status += " in " + res.Latency.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "status\t%s\n", status)
+	}
+	return w.Flush()
+}
+
+// describeFile returns the path with the file it links to if it's a link
+func describeFile(file string) string {
+	if target := kconf.Target(file); target != file {
+		return file + " -> " + target
+	}
+	return file
+}
+
+// credentialExpiry describes the credentials of the user with their expiry when it's known:
+// the end of the validity of the client certificate or the exp claim of the token
+func credentialExpiry(user *User, dir string) string {
+	switch {
+	case user.Exec != nil:
+		return "exec plugin " + user.Exec.Command
+	case user.AuthProvider != nil:
+		return "auth provider " + user.AuthProvider.Name
+	case user.ClientCertificateData != "" || user.ClientCertificate != "":
+		data, err := dataOrFile(user.ClientCertificateData, user.ClientCertificate, dir)
+		if err != nil {
+			return "certificate: " + err.Error()
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return "invalid certificate"
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "invalid certificate"
+		}
+		return "certificate " + describeExpiry(cert.NotAfter)
+	case user.Token != "" || user.TokenFile != "":
+		token := user.Token
+		if token == "" {
+			data, err := os.ReadFile(resolvePath(user.TokenFile, dir))
+			if err != nil {
+				return "token: " + err.Error()
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if exp, ok := tokenExpiry(token); ok {
+			return "token " + describeExpiry(exp)
+		}
+		return "token without expiry"
+	case user.Username != "":
+		return "basic auth"
+	}
+	return "no credentials"
+}
+
+// tokenExpiry returns the exp claim of the token if it's a JWT
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// describeExpiry returns the expiry time with the time left in a short human readable form
+func describeExpiry(t time.Time) string {
+	d := time.Until(t)
+	if d <= 0 {
+		return "expired " + humanizeSince(t)
+	}
+
+	left := strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	switch {
+	case d < time.Hour:
+		left = strconv.Itoa(int(d/time.Minute)) + "m"
+	case d < 24*time.Hour:
+		left = strconv.Itoa(int(d/time.Hour)) + "h"
+	}
+	return "expires " + t.Local().Format(time.RFC3339) + ", in " + left
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// the shell syntaxes of the printed commands
+const (
+	shellAuto       = "auto"
+	shellPOSIX      = "posix"
+	shellFish       = "fish"
+	shellPowerShell = "powershell"
+)
+
+// shellSyntaxes are the syntaxes of the known shells by their program names
+var shellSyntaxes = map[string]string{
+	"sh":         shellPOSIX,
+	"bash":       shellPOSIX,
+	"zsh":        shellPOSIX,
+	"dash":       shellPOSIX,
+	"ksh":        shellPOSIX,
+	"ash":        shellPOSIX,
+	"fish":       shellFish,
+	"pwsh":       shellPowerShell,
+	"powershell": shellPowerShell,
+}
+
+// shellSyntax returns the syntax of the commands evaluated by the caller: the one given by --shell,
+// the one of the parent process if it's a known shell, of $SHELL otherwise
+func (c *Config) shellSyntax() string {
+	if c.Shell != shellAuto {
+		return c.Shell
+	}
+	if c.detectedShell == "" {
+		c.detectedShell = detectShell()
+		debugf("shell syntax: %s", c.detectedShell)
+	}
+	return c.detectedShell
+}
+
+// detectShell guesses the syntax of the calling shell
+func detectShell() string {
+	// the command substitution runs in a child of the shell
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", os.Getppid())); err == nil {
+		if syntax, ok := shellSyntaxes[shellName(string(comm))]; ok {
+			return syntax
+		}
+	}
+	if syntax, ok := shellSyntaxes[shellName(os.Getenv("SHELL"))]; ok {
+		return syntax
+	}
+	if runtime.GOOS == "windows" {
+		return shellPowerShell
+	}
+	return shellPOSIX
+}
+
+// shellName returns the program name of the shell: login shells are prefixed with a dash
+func shellName(program string) string {
+	name := strings.TrimSuffix(filepath.Base(strings.TrimSpace(program)), ".exe")
+	return strings.TrimPrefix(name, "-")
+}
+
+// exportCommand returns the command setting the environment variable in the caller shell
+func (c *Config) exportCommand(name, value string) string {
+	switch c.shellSyntax() {
+	case shellFish:
+		return "set -gx " + name + " " + fishQuote(value)
+	case shellPowerShell:
+		return "$env:" + name + " = " + powerShellQuote(value)
+	}
+	return "export " + name + "=" + shellQuote(value)
+}
+
+// unsetCommand returns the command unsetting the environment variable in the caller shell
+func (c *Config) unsetCommand(name string) string {
+	switch c.shellSyntax() {
+	case shellFish:
+		return "set -e " + name
+	case shellPowerShell:
+		return "Remove-Item Env:" + name + " -ErrorAction SilentlyContinue"
+	}
+	return "unset " + name
+}
+
+// fishQuote returns the string as a single fish word: the single quoted strings of fish escape the backslashes and the quotes
+func fishQuote(s string) string {
+	if safeShellWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// powerShellQuote returns the string as a PowerShell verbatim string: nothing but the doubled single quotes is special in it,
+// the typographic ones included
+func powerShellQuote(s string) string {
+	for _, q := range []string{"'", "\u2018", "\u2019", "\u201a", "\u201b"} {
+		s = strings.ReplaceAll(s, q, q+q)
+	}
+	return "'" + s + "'"
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// tagKubeconfig sets the key=value tags of the entry or deletes the given tag keys
+func (c *Config) tagKubeconfig(configPath string, args []string) error {
+	if len(args) < 2 {
+		return kconf.InvalidErrorf("not enough arguments")
+	}
+
+	tags := map[string]string{}
+	for _, arg := range args[1:] {
+		if c.Delete {
+			tags[arg] = ""
+			continue
+		}
+		k, v, err := parseTag(arg)
+		if err != nil {
+			return err
+		}
+		tags[k] = v
+	}
+
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if e.Meta.Tags == nil {
+			e.Meta.Tags = map[string]string{}
+		}
+		for k, v := range tags {
+			if c.Delete {
+				delete(e.Meta.Tags, k)
+			} else {
+				e.Meta.Tags[k] = v
+			}
+		}
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+
+		c.infof("%s tags: %s\n", e.Name, formatTags(e.Meta.Tags))
+		return nil
+	})
+}
+
+// formatTags returns the tags as sorted key=value pairs
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// matchTags returns true if the entry has any of the given tags
+func matchTags(tags, selector map[string]string) bool {
+	for k, v := range selector {
+		if tv, ok := tags[k]; ok && tv == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// templateVars are the key=value variables of the template given by the repeated --var flags
+type templateVars map[string]string
+
+func (v templateVars) String() string {
+	return formatTags(v)
+}
+
+func (v templateVars) Set(kv string) error {
+	parts := strings.SplitN(kv, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value: %q", kv)
+	}
+	v[parts[0]] = parts[1]
+	return nil
+}
+
+// manageTemplate renders the Go template kubeconfig with the variables and adds the result to the library.
+// The rendered kubeconfig has no other file than its copy in the library.
+func (c *Config) manageTemplate(configPath string, args []string) error {
+	if len(args) == 0 || args[0] != "render" {
+		return kconf.InvalidErrorf("unknown template operation, expected: render")
+	}
+	args = args[1:]
+	if len(args) < 1 {
+		return kconf.InvalidErrorf("expected the template file")
+	}
+
+	tmpl, err := template.New(filepath.Base(args[0])).Option("missingkey=error").ParseFiles(args[0])
+	if err != nil {
+		return kconf.InvalidErrorf("invalid template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, map[string]string(c.Vars)); err != nil {
+		return kconf.InvalidErrorf("cannot render template: %w", err)
+	}
+
+	kc, err := parseKubeconfig(buf.Bytes())
+	if err != nil {
+		return kconf.InvalidErrorf("rendered template is not a kubeconfig: %w", err)
+	}
+	if len(kc.Clusters) == 0 && len(kc.Contexts) == 0 {
+		return kconf.InvalidErrorf("rendered template is not a kubeconfig: no clusters or contexts")
+	}
+	var name string
+	if len(args) > 1 {
+		name = args[1]
+	} else if name, err = contextName(kc); err != nil {
+		return err
+	}
+
+	if c.DryRun {
+		fmt.Printf("%s would be added:\n%s", name, buf.String())
+		return nil
+	}
+
+	replace, err := c.addData(buf.Bytes(), name)
+	if err != nil {
+		return err
+	}
+	if replace {
+		c.infof("%s rendered from %s replaced\n", name, args[0])
+	} else {
+		c.infof("%s rendered from %s added\n", name, args[0])
+	}
+	return nil
+}
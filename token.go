@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const defaultTokenDuration = time.Hour
+
+// tokenRequest is the TokenRequest resource of the service account token API
+type tokenRequest struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		ExpirationSeconds int64 `json:"expirationSeconds"`
+	} `json:"spec"`
+	Status struct {
+		Token               string    `json:"token"`
+		ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+	} `json:"status"`
+}
+
+// mintToken requests a short-lived token of the --sa service account with the credentials of the entry.
+// The token is printed or, with --add, used by a derived entry.
+func (c *Config) mintToken(configPath string, args []string) error {
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the kubeconfig name")
+	}
+	parts := strings.Split(c.ServiceAccount, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return kconf.InvalidErrorf("invalid service account %q, expected namespace/name", c.ServiceAccount)
+	}
+	if c.Duration < 10*time.Minute {
+		// the API server rejects the shorter ones
+		return kconf.InvalidErrorf("duration must be at least 10m: %s", c.Duration)
+	}
+
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	e, err := c.findEntry(entries, args[0])
+	if err != nil {
+		return err
+	}
+	kc, err := loadKubeconfig(e.Path)
+	if err != nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	client, err := newAPIClient(kc, e.Path, c.Net)
+	if err != nil {
+		return err
+	}
+
+	req := &tokenRequest{APIVersion: "authentication.k8s.io/v1", Kind: "TokenRequest"}
+	req.Spec.ExpirationSeconds = int64(c.Duration / time.Second)
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	apiPath := fmt.Sprintf("/api/v1/namespaces/%s/serviceaccounts/%s/token", parts[0], parts[1])
+	res := &tokenRequest{}
+	if err = client.call(http.MethodPost, apiPath, body, http.StatusCreated, res); err != nil {
+		return fmt.Errorf("cannot request token of %s: %w", c.ServiceAccount, err)
+	}
+
+	if c.AddAs == "" {
+		if c.Output == outputJSON {
+			return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+				"token":      res.Status.Token,
+				"expiration": res.Status.ExpirationTimestamp,
+			})
+		}
+		fmt.Println(res.Status.Token)
+		return nil
+	}
+
+	// the derived entry reaches the same clusters as the service account
+	absolutePaths(kc, filepath.Dir(kconf.Target(e.Path)))
+	kc.setUser(&NamedUser{Name: parts[1], User: User{Token: res.Status.Token}})
+	data, err := kc.marshal()
+	if err != nil {
+		return err
+	}
+	unlock, err := c.Library.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err = c.addData(data, c.AddAs); err != nil {
+		return err
+	}
+	c.infof("%s added with token of %s expiring %s\n", c.AddAs, c.ServiceAccount, res.Status.ExpirationTimestamp.Local().Format(time.RFC3339))
+	return nil
+}
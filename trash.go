@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// autoTrash moves the entries whose kubeconfigs are missing for more than the days of the trash settings to the trash.
+// It runs before any command, the entries found missing for the first time are only recorded.
+func (c *Config) autoTrash() {
+	days := c.Settings.Trash.MissingDays
+	if days <= 0 {
+		return
+	}
+
+	unlock, err := c.Library.Lock()
+	if err != nil {
+		debugf("cannot lock the library to trash the missing kubeconfigs: %v", err)
+		return
+	}
+	defer unlock()
+
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	deadline := now.AddDate(0, 0, -days)
+	changed := false
+	for _, e := range entries {
+		_, err := os.Stat(e.Path)
+		switch {
+		case err == nil:
+			if e.Meta.MissingSince != nil {
+				debugf("kubeconfig of %s is back", e.Name)
+				e.Meta.MissingSince = nil
+				changed = true
+			}
+		case !os.IsNotExist(err):
+			debugf("cannot check the kubeconfig of %s: %v", e.Name, err)
+		case e.Meta.MissingSince == nil:
+			debugf("kubeconfig of %s is missing", e.Name)
+			since := now
+			e.Meta.MissingSince = &since
+			changed = true
+		case e.Meta.MissingSince.Before(deadline) && !e.Meta.Protected:
+			target := kconf.Target(e.Path)
+			if err = c.Library.Trash(e, meta); err != nil {
+				debugf("cannot trash %s: %v", e.Name, err)
+				continue
+			}
+			removeWorkCopies(c.Library.Path, e.Name)
+			changed = true
+			// stderr keeps the output of the command clean, the shell commands of set for instance
+			fmt.Fprintf(os.Stderr, "%s -> %s moved to trash, missing since %s\n", e.Name, target, e.Meta.MissingSince.Format(time.RFC3339))
+		}
+	}
+	if changed {
+		if err = c.Library.Save(meta); err != nil {
+			debugf("cannot save the missing kubeconfigs: %v", err)
+		}
+	}
+}
+
+// trash lists the trashed entries, restores them (trash restore <name>...) or empties the trash (trash empty)
+func (c *Config) trash(configPath string, args []string) error {
+	_, meta, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		names := make([]string, 0, len(meta.Trash))
+		for name := range meta.Trash {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			t := meta.Trash[name]
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, humanizeSince(t.TrashedAt), t.Link)
+		}
+		return w.Flush()
+	}
+
+	switch args[0] {
+	case "restore":
+		if len(args) < 2 {
+			return kconf.InvalidErrorf("expected the names of the trashed kubeconfigs")
+		}
+		for _, name := range args[1:] {
+			if c.DryRun {
+				if _, ok := meta.Trash[name]; !ok {
+					return kconf.EntryError(name, kconf.NotFoundErrorf("kubeconfig not found in trash: %s", name))
+				}
+				fmt.Printf("%s would be restored\n", name)
+				continue
+			}
+			if err = c.Library.Restore(name, meta); err != nil {
+				return err
+			}
+			c.infof("%s restored\n", name)
+		}
+	case "empty":
+		if len(args) != 1 {
+			return kconf.InvalidErrorf("unexpected arguments: %v", args[1:])
+		}
+		if len(meta.Trash) == 0 {
+			return nil
+		}
+		if c.DryRun {
+			fmt.Printf("%d kubeconfigs would be deleted from trash\n", len(meta.Trash))
+			return nil
+		}
+		if !c.Yes && !confirm(fmt.Sprintf("Delete %d kubeconfigs from trash?", len(meta.Trash))) {
+			return fmt.Errorf("aborted")
+		}
+		c.infof("%d kubeconfigs deleted from trash\n", len(meta.Trash))
+		meta.Trash = nil
+	default:
+		return kconf.InvalidErrorf("unknown trash operation %q, expected: restore, empty", args[0])
+	}
+	if c.DryRun {
+		return nil
+	}
+	return c.Library.Save(meta)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// treeNode is a directory or an entry of the hierarchical names
+type treeNode struct {
+	name     string
+	entry    *kconf.Entry
+	children map[string]*treeNode
+}
+
+// printTree prints the entries as a tree of their hierarchical names, the directories first
+func (c *Config) printTree(entries []*kconf.Entry, currName, marker string) {
+	root := &treeNode{children: map[string]*treeNode{}}
+	for _, e := range entries {
+		node := root
+		parts := strings.Split(e.Name, "/")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node.children[part+"/"]
+			if !ok {
+				child = &treeNode{name: part + "/", children: map[string]*treeNode{}}
+				node.children[part+"/"] = child
+			}
+			node = child
+		}
+		leaf := parts[len(parts)-1]
+		node.children[leaf] = &treeNode{name: leaf, entry: e}
+	}
+
+	blank := strings.Repeat(" ", utf8.RuneCountInString(marker)+1)
+	colored := c.colorEnabled()
+	var walk func(node *treeNode, indent string)
+	walk = func(node *treeNode, indent string) {
+		for i, child := range node.sortedChildren() {
+			branch, next := "├── ", "│   "
+			if i == len(node.children)-1 {
+				branch, next = "└── ", "    "
+			}
+			if indent == "" && node == root {
+				branch, next = "", ""
+			}
+			if child.entry == nil {
+				fmt.Printf("%s%s%s\n", blank, indent+branch, child.name)
+				walk(child, indent+next)
+				continue
+			}
+
+			e := child.entry
+			star := blank
+			var colors []string
+			if colored {
+				colors = append(colors, c.tagColor(e.Meta.Tags))
+			}
+			if e.Name == currName {
+				star = marker + " "
+				if colored {
+					colors = append(colors, c.Settings.Theme.ActiveColor)
+				}
+			}
+			line := fmt.Sprintf("%s%s%s) %s\n", star, indent+branch, indexLabel(e), child.name)
+			if colored {
+				line = colorize(line, colors...)
+			}
+			fmt.Print(line)
+		}
+	}
+	walk(root, "")
+}
+
+// sortedChildren returns the children of the directory: the subdirectories then the entries, by name
+func (n *treeNode) sortedChildren() []*treeNode {
+	res := make([]*treeNode, 0, len(n.children))
+	for _, child := range n.children {
+		res = append(res, child)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if (res[i].entry == nil) != (res[j].entry == nil) {
+			return res[i].entry == nil
+		}
+		return res[i].name < res[j].name
+	})
+	return res
+}
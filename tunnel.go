@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	// tunnelSocketExt is the extension of the control sockets of the SSH connections, next to the working copies
+	tunnelSocketExt   = ".sock"
+	defaultServerPort = "443"
+)
+
+// tunnelKubeconfig declares the SSH tunnel of the entry or deletes it
+func (c *Config) tunnelKubeconfig(configPath string, args []string) error {
+	if c.Delete {
+		if len(args) != 1 {
+			return kconf.InvalidErrorf("expected the kubeconfig name")
+		}
+	} else if len(args) != 2 {
+		return kconf.InvalidErrorf("expected the kubeconfig name and the jump host")
+	}
+	if !c.Delete {
+		if err := validateTunnel(&kconf.Tunnel{Host: args[1], LocalPort: c.LocalPort, Remote: c.Remote}); err != nil {
+			return err
+		}
+	}
+
+	return c.makeKubeconfig(configPath, args[:1], func(e *kconf.Entry, meta *kconf.Metadata) error {
+		if c.Delete {
+			if e.Meta.Tunnel != nil {
+				c.closeTunnel(configPath, e.Name, e.Meta.Tunnel)
+			}
+			e.Meta.Tunnel = nil
+		} else {
+			if e.Meta.Proxy != "" {
+				return kconf.EntryError(e.Name, kconf.InvalidErrorf("kubeconfig %q has a proxy", e.Name))
+			}
+			e.Meta.Tunnel = &kconf.Tunnel{Host: args[1], LocalPort: c.LocalPort, Remote: c.Remote}
+		}
+		if err := c.Library.Save(meta); err != nil {
+			return err
+		}
+
+		if c.Delete {
+			c.infof("%s tunnel removed\n", e.Name)
+		} else {
+			c.infof("%s tunneled through %s\n", e.Name, args[1])
+		}
+		return nil
+	})
+}
+
+// validateTunnel checks the tunnel: the host is given to ssh, it must not be taken for an option
+func validateTunnel(t *kconf.Tunnel) error {
+	if t.Host == "" || strings.HasPrefix(t.Host, "-") || strings.ContainsAny(t.Host, " \t\n") {
+		return kconf.InvalidErrorf("invalid jump host %q", t.Host)
+	}
+	if t.LocalPort < 0 || t.LocalPort > 65535 {
+		return kconf.InvalidErrorf("invalid local port: %d", t.LocalPort)
+	}
+	if t.Remote != "" {
+		if _, _, err := net.SplitHostPort(t.Remote); err != nil {
+			return kconf.InvalidErrorf("invalid remote %q: %w", t.Remote, err)
+		}
+	}
+	return nil
+}
+
+// openTunnel starts the SSH tunnel of the entry unless it's already running
+// and returns the working copy of the kubeconfig pointing to the local end of the tunnel, and whether it was started
+func (c *Config) openTunnel(configPath string, e *kconf.Entry) (string, bool, error) {
+	t := e.Meta.Tunnel
+	file := tunnelFile(configPath, e.Name)
+	socket := file + tunnelSocketExt
+	if exists(file) && exists(socket) && exec.Command("ssh", "-S", socket, "-O", "check", t.Host).Run() == nil {
+		debugf("tunnel of %q already running", e.Name)
+		return file, false, nil
+	}
+
+	kc, err := loadKubeconfig(e.Path)
+	if err != nil {
+		return "", false, kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	if e.Meta.Patch != "" {
+		if kc, err = applyPatch(kc, e.Meta.Patch); err != nil {
+			return "", false, kconf.EntryError(e.Name, err)
+		}
+	}
+	if err = resolveSecrets(kc); err != nil {
+		return "", false, kconf.EntryError(e.Name, err)
+	}
+	cluster := kc.currentCluster()
+	if cluster == nil || cluster.Server == "" {
+		return "", false, kconf.EntryError(e.Name, kconf.InvalidErrorf("no server for context %q", kc.CurrentContext))
+	}
+	server, err := url.Parse(cluster.Server)
+	if err != nil {
+		return "", false, kconf.EntryError(e.Name, kconf.InvalidErrorf("invalid server %q: %w", cluster.Server, err))
+	}
+
+	remote := t.Remote
+	if remote == "" {
+		port := server.Port()
+		if port == "" {
+			port = defaultServerPort
+		}
+		remote = net.JoinHostPort(server.Hostname(), port)
+	}
+	port := t.LocalPort
+	if port == 0 {
+		if port, err = freePort(); err != nil {
+			return "", false, err
+		}
+	}
+
+	if err = os.MkdirAll(path.Dir(file), confDirFileMode); err != nil {
+		return "", false, err
+	}
+	// a dead connection leaves its socket behind
+	os.Remove(socket)
+	forward := fmt.Sprintf("127.0.0.1:%d:%s", port, remote)
+	debugf("ssh -L %s %s", forward, t.Host)
+	cmd := exec.Command("ssh", "-f", "-N", "-M", "-S", socket, "-o", "ExitOnForwardFailure=yes", "-L", forward, t.Host)
+	// the backgrounded ssh must not keep stdout open: it's read by the shell
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", false, fmt.Errorf("cannot open tunnel through %s: %w", t.Host, err)
+	}
+
+	// the certificate of the API server is still checked against its name
+	if cluster.TLSServerName == "" {
+		cluster.TLSServerName = server.Hostname()
+	}
+	server.Host = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	cluster.Server = server.String()
+	if err = writeWorkCopy(file, kc, e); err != nil {
+		return "", false, err
+	}
+	// stdout is evaluated by the shell
+	if !c.Quiet {
+		fmt.Fprintf(os.Stderr, "%s tunneled through %s on port %d\n", e.Name, t.Host, port)
+	}
+	return file, true, nil
+}
+
+// closeTunnel stops the SSH tunnel of the entry and deletes its working copy, the failures are only logged
+func (c *Config) closeTunnel(configPath, name string, t *kconf.Tunnel) {
+	file := tunnelFile(configPath, name)
+	socket := file + tunnelSocketExt
+	if exists(socket) {
+		debugf("closing tunnel of %q", name)
+		if err := exec.Command("ssh", "-S", socket, "-O", "exit", t.Host).Run(); err != nil {
+			debugf("cannot close tunnel of %q: %v", name, err)
+		}
+		os.Remove(socket)
+	}
+	os.Remove(file)
+}
+
+// freePort returns a local TCP port nobody listens on
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
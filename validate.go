@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// Validation results
+const (
+	validationValid   = "valid"
+	validationInvalid = "invalid"
+)
+
+// validationResult is the validation result of an entry
+type validationResult struct {
+	Name     string   `json:"name"`
+	Result   string   `json:"result"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// validateKubeconfigs parses the kubeconfigs of the given entries concurrently (the current kubeconfig by default, all the entries with --all)
+// and checks the references: the contexts, clusters and users, the certificate and token files, the certificate data
+func (c *Config) validateKubeconfigs(configPath string, args []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	targets, err := c.targetEntries(entries, args)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	results := make([]validationResult, 0, len(targets))
+	forEachConcurrently(targets, c.Net.Workers, func(e *kconf.Entry) {
+		res := validationResult{Name: e.Name, Result: validationValid, Problems: validateKubeconfig(e)}
+		if len(res.Problems) > 0 {
+			res.Result = validationInvalid
+		}
+		mu.Lock()
+		results = append(results, res)
+		mu.Unlock()
+	})
+	order := map[string]int{}
+	for i, e := range targets {
+		order[e.Name] = i
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return order[results[i].Name] < order[results[j].Name]
+	})
+
+	var invalid []string
+	for _, res := range results {
+		if res.Result == validationInvalid {
+			invalid = append(invalid, res.Name)
+		}
+	}
+
+	if c.Output == outputJSON {
+		if err = json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, res := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", res.Name, res.Result, strings.Join(res.Problems, "; "))
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(invalid) > 0 {
+		return kconf.InvalidErrorf("%d of %d kubeconfigs invalid: %s", len(invalid), len(results), strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// validateKubeconfig returns the problems of the kubeconfig of the entry,
+// the relative file paths are resolved from the directory of the kubeconfig the entry points to
+func validateKubeconfig(e *kconf.Entry) []string {
+	kc, err := loadKubeconfig(e.Path)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot parse kubeconfig: %v", err)}
+	}
+
+	var res []string
+	addf := func(format string, a ...interface{}) {
+		res = append(res, fmt.Sprintf(format, a...))
+	}
+	if len(kc.Contexts) == 0 {
+		addf("no contexts")
+	}
+	if kc.CurrentContext != "" && kc.context(kc.CurrentContext) == nil {
+		addf("current context %s not found", kc.CurrentContext)
+	}
+	for _, nc := range kc.Contexts {
+		if kc.cluster(nc.Context.Cluster) == nil {
+			addf("cluster %s of context %s not found", nc.Context.Cluster, nc.Name)
+		}
+		if nc.Context.User != "" && kc.user(nc.Context.User) == nil {
+			addf("user %s of context %s not found", nc.Context.User, nc.Name)
+		}
+	}
+
+	dir := filepath.Dir(kconf.Target(e.Path))
+	checkFile := func(subject, field, file string) {
+		if file == "" {
+			return
+		}
+		if _, err := os.Stat(resolvePath(file, dir)); err != nil {
+			addf("%s of %s: %v", field, subject, err)
+		}
+	}
+	checkData := func(subject, field, data string) {
+		if data == "" {
+			return
+		}
+		if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+			addf("%s of %s: %v", field, subject, err)
+		}
+	}
+	for _, nc := range kc.Clusters {
+		subject := "cluster " + nc.Name
+		if nc.Cluster.Server == "" {
+			addf("%s has no server", subject)
+		}
+		checkFile(subject, "certificate-authority", nc.Cluster.CertificateAuthority)
+		checkData(subject, "certificate-authority-data", nc.Cluster.CertificateAuthorityData)
+	}
+	for _, nu := range kc.Users {
+		subject := "user " + nu.Name
+		checkFile(subject, "client-certificate", nu.User.ClientCertificate)
+		checkFile(subject, "client-key", nu.User.ClientKey)
+		checkFile(subject, "tokenFile", nu.User.TokenFile)
+		checkData(subject, "client-certificate-data", nu.User.ClientCertificateData)
+		checkData(subject, "client-key-data", nu.User.ClientKeyData)
+	}
+	return res
+}
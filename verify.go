@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+// verifyResult is the verification result of an entry
+type verifyResult struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Result string `json:"result"`
+}
+
+// verifyKubeconfigs reports the entries whose kubeconfigs changed or disappeared since they were added
+// (the current kubeconfig by default, all the entries with --all)
+func (c *Config) verifyKubeconfigs(configPath string, args []string) error {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return err
+	}
+	targets, err := c.targetEntries(entries, args)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	results := make([]verifyResult, 0, len(targets))
+	for _, e := range targets {
+		res := verifyResult{Name: e.Name, Target: kconf.Target(e.Path), Result: c.Library.Verify(e)}
+		switch res.Result {
+		case kconf.VerifyChanged, kconf.VerifyMissing:
+			failed = append(failed, e.Name)
+		}
+		results = append(results, res)
+	}
+
+	if c.Output == outputJSON {
+		if err = json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, res := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", res.Name, res.Result, res.Target)
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d kubeconfigs changed or missing: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// Build metadata, injected with ldflags:
+//
+//	go build -ldflags "-X main.version=v1.0.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// versionInfo is the build metadata of the program
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+func (c *Config) printVersion(configPath string, args []string) error {
+	info := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if c.Output == outputJSON {
+		return json.NewEncoder(os.Stdout).Encode(info)
+	}
+
+	fmt.Printf("kconf %s\ncommit: %s\nbuilt: %s\ngo: %s %s\n", info.Version, info.Commit, info.BuildDate, info.GoVersion, info.Platform)
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchSettleDelay is the time a dropped file must stay unchanged before it's added:
+// the files are often written in several chunks
+const watchSettleDelay = 500 * time.Millisecond
+
+// watchDirectory adds the kubeconfigs dropped in the directory until interrupted
+func (c *Config) watchDirectory(configPath string, args []string) error {
+	if len(args) != 1 {
+		return kconf.InvalidErrorf("expected the directory to watch")
+	}
+	dir, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return kconf.InvalidErrorf("not a directory: %s", dir)
+	}
+
+	pattern := c.Settings.Watch.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+	if _, err = path.Match(pattern, ""); err != nil {
+		return kconf.InvalidErrorf("invalid watch pattern %q: %w", pattern, err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err = watcher.Add(dir); err != nil {
+		return err
+	}
+	c.infof("watching %s\n", dir)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	// the pending files are added once they stop changing
+	var mu, adding sync.Mutex
+	pending := map[string]*time.Timer{}
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, t := range pending {
+			t.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case err := <-watcher.Errors:
+			return err
+		case ev := <-watcher.Events:
+			name := filepath.Base(ev.Name)
+			if ev.Op&(fsnotify.Create|fsnotify.Write) == 0 || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if ok, _ := path.Match(pattern, name); !ok {
+				debugf("ignoring %s: doesn't match %q", ev.Name, pattern)
+				continue
+			}
+
+			file := ev.Name
+			mu.Lock()
+			if t, ok := pending[file]; ok {
+				t.Reset(watchSettleDelay)
+			} else {
+				pending[file] = time.AfterFunc(watchSettleDelay, func() {
+					mu.Lock()
+					delete(pending, file)
+					mu.Unlock()
+					// one drop at a time, the library lock keeps the other processes out
+					adding.Lock()
+					defer adding.Unlock()
+					c.addDropped(configPath, file)
+				})
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// addDropped adds the dropped kubeconfig to the library, the errors are reported without stopping the watch
+func (c *Config) addDropped(configPath, file string) {
+	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+		debugf("ignoring %s: not a regular file", file)
+		return
+	}
+	kc, err := loadKubeconfig(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring %s: not a kubeconfig: %v\n", file, err)
+		return
+	}
+	if len(kc.Clusters) == 0 && len(kc.Contexts) == 0 {
+		fmt.Fprintf(os.Stderr, "ignoring %s: not a kubeconfig: no clusters or contexts\n", file)
+		return
+	}
+
+	// the drops are added concurrently, the name is chosen when no one else can take it
+	unlock, err := c.Library.Lock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot add %s: %v\n", file, err)
+		return
+	}
+	defer unlock()
+
+	name := freeName(configPath, c.Settings.Watch.Prefix+defaultName(file), file)
+	if kconf.Target(path.Join(configPath, name)) == kconf.Target(file) {
+		debugf("%s already added as %q", file, name)
+		return
+	}
+
+	if err = c.addKubeconfig(configPath, []string{file, name}); err != nil {
+		var ke *kconf.Error
+		if errors.As(err, &ke) && ke.Entry != "" {
+			err = fmt.Errorf("%q: %w", ke.Entry, err)
+		}
+		fmt.Fprintf(os.Stderr, "cannot add %s: %v\n", file, err)
+	}
+}
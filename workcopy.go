@@ -0,0 +1,276 @@
+package main
+
+import (
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	// workDir is the directory of the library holding the working copies of the kubeconfigs adjusted for set and exec
+	workDir          = ".work"
+	workCopyFileMode = 0600
+)
+
+// workFile returns the path of the working copy of the entry in the session, of the variant selected by the profile or the context if any:
+// the sessions using different contexts of the entry don't share their copies. The copies are the hidden files of the entry directory.
+func workFile(configPath, name, variant string) string {
+	file := "." + sessionName()
+	if variant != "" {
+		file += "@" + url.PathEscape(variant)
+	}
+	return path.Join(configPath, workDir, name, file)
+}
+
+// tunnelFile returns the path of the working copy of the entry pointing to its tunnel, shared by the sessions like the tunnel
+func tunnelFile(configPath, name string) string {
+	return path.Join(configPath, workDir, name, ".tunnel")
+}
+
+// workVariant returns the variant of the working copy selected by the context, by the profile otherwise (empty if none)
+func workVariant(profile, context string) string {
+	switch {
+	case context != "":
+		return "context=" + context
+	case profile != "":
+		return "profile=" + profile
+	}
+	return ""
+}
+
+// workEntry returns the name of the entry whose working copy is the given file (empty if none)
+func workEntry(configPath, file string) string {
+	rel, err := filepath.Rel(path.Join(configPath, workDir), file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	dir, base := path.Split(filepath.ToSlash(rel))
+	if dir == "" || !strings.HasPrefix(base, ".") {
+		return ""
+	}
+	return strings.TrimSuffix(dir, "/")
+}
+
+// kubeconfigFile returns the kubeconfig set and exec use for the entry: the entry itself or its working copy,
+// and whether a tunnel was started for it. The working copy has the patch and the proxy of the entry and the resolved secret references.
+func (c *Config) kubeconfigFile(configPath string, e *kconf.Entry) (string, bool, error) {
+	if e.Meta.Tunnel != nil {
+		return c.openTunnel(configPath, e)
+	}
+
+	data, err := os.ReadFile(e.Path)
+	secrets := err == nil && hasSecretRefs(data)
+	if e.Meta.Proxy == "" && e.Meta.Patch == "" && !secrets {
+		return e.Path, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	kc, err := parseKubeconfig(data)
+	if err != nil {
+		return "", false, kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	if e.Meta.Patch != "" {
+		if kc, err = applyPatch(kc, e.Meta.Patch); err != nil {
+			return "", false, kconf.EntryError(e.Name, err)
+		}
+		// the patch may add secret references
+		if data, err = kc.marshal(); err != nil {
+			return "", false, err
+		}
+		secrets = hasSecretRefs(data)
+	}
+	if e.Meta.Proxy != "" {
+		for i := range kc.Clusters {
+			kc.Clusters[i].Cluster.ProxyURL = e.Meta.Proxy
+		}
+	}
+	if secrets {
+		if err = resolveSecrets(kc); err != nil {
+			return "", false, kconf.EntryError(e.Name, err)
+		}
+	}
+	file := workFile(configPath, e.Name, "")
+	return file, false, writeWorkCopy(file, kc, e)
+}
+
+// refreshWorkCopy rewrites the working copy set exported for the active entry if the kubeconfig of the entry changed since,
+// with the same profile or context: KUBECONFIG keeps pointing to the up to date content
+func (c *Config) refreshWorkCopy(configPath string) {
+	st := loadState(configPath)
+	if st.Entry == "" || workEntry(configPath, st.File) != st.Entry {
+		return
+	}
+	if !workCopyChanged(configPath, st) {
+		return
+	}
+
+	// the other kconf processes may be changing the entry or its working copies
+	unlock, err := c.Library.Lock()
+	if err != nil {
+		debugf("cannot lock the library to refresh the working copy: %v", err)
+		return
+	}
+	defer unlock()
+	if !workCopyChanged(configPath, st) {
+		return
+	}
+
+	entries, meta, err := c.Library.Entries()
+	if err != nil {
+		return
+	}
+	e, err := kconf.FindName(entries, st.Entry)
+	// the tunnels are opened by set only
+	if err != nil || e.Meta.Tunnel != nil {
+		return
+	}
+	debugf("refreshing working copy %s of the changed kubeconfig", st.File)
+	if err = c.writeActiveCopy(configPath, e, meta, st); err != nil {
+		debugf("cannot refresh working copy %s: %v", st.File, err)
+	}
+}
+
+// workCopyChanged returns true if the kubeconfig of the active entry was changed after its working copy was written
+func workCopyChanged(configPath string, st *activeState) bool {
+	work, err := os.Stat(st.File)
+	if err != nil {
+		return false
+	}
+	// the link is followed to the kubeconfig
+	source, err := os.Stat(path.Join(configPath, st.Entry))
+	return err == nil && source.ModTime().After(work.ModTime())
+}
+
+// writeActiveCopy writes the working copy of the entry with the profile or the context of the state
+func (c *Config) writeActiveCopy(configPath string, e *kconf.Entry, meta *kconf.Metadata, st *activeState) error {
+	file, _, err := c.kubeconfigFile(configPath, e)
+	if err != nil {
+		return err
+	}
+	p := meta.Profiles[st.Profile]
+	if st.Context != "" {
+		p = &kconf.Profile{Entry: e.Name, Context: st.Context}
+	}
+	if p != nil && (p.Context != "" || p.Namespace != "") {
+		file, err = applyProfile(configPath, e, file, p, workVariant(st.Profile, st.Context))
+		if err != nil {
+			return err
+		}
+	}
+	if file == st.File {
+		return nil
+	}
+	// no adjustment is needed anymore but KUBECONFIG still points to the working copy
+	kc, err := loadKubeconfig(file)
+	if err != nil {
+		return kconf.EntryError(e.Name, kconf.InvalidErrorf("cannot parse kubeconfig: %w", err))
+	}
+	return writeWorkCopy(st.File, kc, e)
+}
+
+// writeWorkCopy writes the adjusted kubeconfig of the entry as its working copy
+func writeWorkCopy(file string, kc *Kubeconfig, e *kconf.Entry) error {
+	absolutePaths(kc, filepath.Dir(kconf.Target(e.Path)))
+	data, err := kc.marshal()
+	if err != nil {
+		return err
+	}
+	// the working copies used to be the entry files themselves
+	if info, err := os.Lstat(path.Dir(file)); err == nil && !info.IsDir() {
+		debugf("removing legacy working copy %s", path.Dir(file))
+		os.Remove(path.Dir(file))
+	}
+	if err = os.MkdirAll(path.Dir(file), confDirFileMode); err != nil {
+		return err
+	}
+	return kconf.WriteFileAtomic(file, data, workCopyFileMode)
+}
+
+// removeWorkCopies deletes the working copies of the entry: they may hold resolved secrets.
+// The tunnel sockets are left to closeTunnel.
+func removeWorkCopies(configPath, name string) {
+	for _, file := range workCopies(configPath) {
+		if workEntry(configPath, file) == name {
+			debugf("removing working copy %s", file)
+			os.Remove(file)
+		}
+	}
+}
+
+// workCopies returns the working copies of the library, without the tunnel sockets
+func workCopies(configPath string) []string {
+	var res []string
+	_ = filepath.WalkDir(path.Join(configPath, workDir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(p, tunnelSocketExt) {
+			return nil
+		}
+		res = append(res, p)
+		return nil
+	})
+	return res
+}
+
+// collectWorkCopies returns the working copies of the entries not in the library anymore
+// and the ones older than the given age no session points to, removes them unless dryRun is set
+func (c *Config) collectWorkCopies(configPath string, olderThan time.Duration, dryRun bool) ([]string, error) {
+	entries, _, err := c.Library.Entries()
+	if err != nil {
+		return nil, err
+	}
+	present := map[string]bool{}
+	for _, e := range entries {
+		present[e.Name] = true
+	}
+	used := map[string]bool{}
+	for _, st := range sessionStates(configPath) {
+		used[st.File] = true
+	}
+
+	var garbage []string
+	deadline := time.Now().Add(-olderThan)
+	for _, file := range workCopies(configPath) {
+		if used[file] || exists(file+tunnelSocketExt) {
+			continue
+		}
+		info, err := os.Stat(file)
+		if present[workEntry(configPath, file)] && (err != nil || !info.ModTime().Before(deadline)) {
+			continue
+		}
+		garbage = append(garbage, file)
+	}
+	if dryRun {
+		return garbage, nil
+	}
+	for i, file := range garbage {
+		debugf("removing %s", file)
+		if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return garbage[:i], err
+		}
+	}
+	return garbage, nil
+}
+
+// absolutePaths makes the file paths of the kubeconfig absolute for it to be usable from another directory
+func absolutePaths(kc *Kubeconfig, dir string) {
+	abs := func(file *string) {
+		if *file != "" {
+			*file = resolvePath(*file, dir)
+		}
+	}
+	for i := range kc.Clusters {
+		abs(&kc.Clusters[i].Cluster.CertificateAuthority)
+	}
+	for i := range kc.Users {
+		u := &kc.Users[i].User
+		abs(&u.ClientCertificate)
+		abs(&u.ClientKey)
+		abs(&u.TokenFile)
+	}
+}
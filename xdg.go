@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/alebedev87/kconf/pkg/kconf"
+)
+
+const (
+	xdgDataVar   = "XDG_DATA_HOME"
+	xdgConfigVar = "XDG_CONFIG_HOME"
+	xdgStateVar  = "XDG_STATE_HOME"
+	// xdgAppDir is the directory of kconf in the XDG base directories
+	xdgAppDir = "kconf"
+	// xdgSettingsFile is the config file in the XDG config directory
+	xdgSettingsFile = "config.json"
+)
+
+// The XDG directories of the config file and the state, in the library if empty:
+// the libraries given by KCONF_LIBRARY_PATH or --library hold everything
+var (
+	settingsDir string
+	stateRoot   string
+)
+
+// xdgDir returns the kconf directory of the XDG base directory given by the environment variable, the default one relative to the home directory otherwise
+func xdgDir(home, envVar, defaultDir string) string {
+	// the relative paths are invalid per the specification
+	if dir := os.Getenv(envVar); filepath.IsAbs(dir) {
+		return path.Join(filepath.ToSlash(dir), xdgAppDir)
+	}
+	return path.Join(home, defaultDir, xdgAppDir)
+}
+
+// xdgLibrary returns the library in the XDG data directory, the config file and the state go to the XDG config and state directories.
+// The legacy library of the home directory is moved there if it's the only one, a shared root stays in place.
+func xdgLibrary(home string) (string, error) {
+	legacy := path.Join(home, defaultConfigDir)
+	data := xdgDir(home, xdgDataVar, ".local/share")
+	if exists(path.Join(legacy, sharedMarker)) {
+		debugf("config path from home directory: %s", legacy)
+		return userLibrary(legacy)
+	}
+
+	if isLegacyDir(legacy) && !exists(data) {
+		moved, err := migrateLegacyLibrary(home, legacy, data)
+		switch {
+		case err != nil && !moved:
+			// a library on another file system for instance
+			debugf("cannot move legacy library %s to %s, keeping it: %v", legacy, data, err)
+			return legacy, nil
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "library moved from %s to %s, run kconf set again in the open shells: %v\n", legacy, data, err)
+		case moved:
+			fmt.Fprintf(os.Stderr, "library moved from %s to %s\n", legacy, data)
+		}
+	}
+	if isLegacyDir(legacy) && exists(data) {
+		debugf("ignoring legacy library %s, %s exists", legacy, data)
+	}
+
+	settingsDir = xdgDir(home, xdgConfigVar, ".config")
+	stateRoot = xdgDir(home, xdgStateVar, ".local/state")
+	debugf("config path from XDG data directory: %s", data)
+	if exists(data) {
+		return data, nil
+	}
+	debugf("creating config directory %s", data)
+	return data, os.MkdirAll(data, confDirFileMode)
+}
+
+// isLegacyDir returns true if the legacy library is a directory, not the link to the moved one
+func isLegacyDir(legacy string) bool {
+	info, err := os.Lstat(legacy)
+	return err == nil && info.IsDir()
+}
+
+// migrateLegacyLibrary moves the legacy library to the XDG data directory, its config file and state to the XDG config and state directories,
+// under the lock of the library. The legacy directory becomes a link to the new one for the open shells, the links to the files of the library,
+// the relative links to the files outside of it, the trashed entries and the files of the states are updated.
+// Returns whether the library was moved: the errors after the move are not fatal.
+func migrateLegacyLibrary(home, legacy, data string) (bool, error) {
+	unlock, err := kconf.New(legacy).Lock()
+	if err != nil {
+		return false, err
+	}
+	// the lock file moves along with the library
+	defer unlock()
+	// moved by another kconf while waiting for the lock
+	if !isLegacyDir(legacy) || exists(data) {
+		return false, nil
+	}
+
+	if err = os.MkdirAll(path.Dir(data), confDirFileMode); err != nil {
+		return false, err
+	}
+	if err = os.Rename(legacy, data); err != nil {
+		return false, err
+	}
+	debugf("moved legacy library %s to %s", legacy, data)
+	if err = os.Symlink(data, legacy); err != nil {
+		return true, err
+	}
+
+	err = filepath.WalkDir(data, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		target, err := os.Readlink(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(data, p)
+		if err != nil {
+			return err
+		}
+		updated, err := relocateLink(target, filepath.Join(legacy, rel), p, legacy, data)
+		if err != nil || updated == target {
+			return err
+		}
+		debugf("relink %s -> %s", p, updated)
+		return kconf.ReplaceSymlink(updated, p)
+	})
+	if err != nil {
+		return true, err
+	}
+	if err = relocateTrash(legacy, data); err != nil {
+		return true, err
+	}
+
+	state := xdgDir(home, xdgStateVar, ".local/state")
+	moves := map[string]string{
+		path.Join(data, settingsFile): path.Join(xdgDir(home, xdgConfigVar, ".config"), xdgSettingsFile),
+		path.Join(data, stateDir):     state,
+	}
+	for from, to := range moves {
+		if !exists(from) || exists(to) {
+			continue
+		}
+		if err = os.MkdirAll(path.Dir(to), confDirFileMode); err != nil {
+			return true, err
+		}
+		debugf("move %s to %s", from, to)
+		if err = os.Rename(from, to); err != nil {
+			return true, err
+		}
+	}
+	return true, relocateStates(state, legacy, data)
+}
+
+// relocateLink returns the target of the link moved from the legacy library to the new one:
+// the absolute targets in the legacy library are moved to the new one, the relative ones outside of it keep pointing to the same files
+func relocateLink(target, oldLink, newLink, legacy, data string) (string, error) {
+	abs := target
+	if !filepath.IsAbs(target) {
+		abs = filepath.Join(filepath.Dir(oldLink), target)
+	}
+	inside := abs == legacy || strings.HasPrefix(abs, legacy+string(filepath.Separator))
+	switch {
+	case inside && filepath.IsAbs(target):
+		return filepath.Join(data, strings.TrimPrefix(abs, legacy)), nil
+	case !inside && !filepath.IsAbs(target):
+		return filepath.Rel(filepath.Dir(newLink), abs)
+	}
+	return target, nil
+}
+
+// relocateTrash updates the links of the trashed entries of the moved library
+func relocateTrash(legacy, data string) error {
+	lib := kconf.New(data)
+	meta, err := lib.Store.Load()
+	if err != nil || len(meta.Trash) == 0 {
+		return err
+	}
+	for name, t := range meta.Trash {
+		if t.Link, err = relocateLink(t.Link, filepath.Join(legacy, name), filepath.Join(data, name), legacy, data); err != nil {
+			return err
+		}
+	}
+	return lib.Save(meta)
+}
+
+// relocateStates updates the files of the library recorded in the states of the sessions
+func relocateStates(dir, legacy, data string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, f := range files {
+		file := path.Join(dir, f.Name())
+		if f.IsDir() || path.Ext(file) != ".json" {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		st := &activeState{}
+		if err = json.Unmarshal(content, st); err != nil {
+			debugf("ignoring corrupted state %s: %v", file, err)
+			continue
+		}
+		if !strings.HasPrefix(st.File, legacy+string(filepath.Separator)) {
+			continue
+		}
+		st.File = filepath.Join(data, strings.TrimPrefix(st.File, legacy))
+		if content, err = json.Marshal(st); err != nil {
+			return err
+		}
+		debugf("state %s points to %s", file, st.File)
+		if err = kconf.WriteFileAtomic(file, content, stateFileMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}